@@ -2,17 +2,25 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math"
 	"net"
 	"net/http"
+	"net/http/httptrace"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Azure/go-ntlmssp"
+	"github.com/santhosh-tekuri/jsonschema/v6"
 )
 
 const (
@@ -21,6 +29,12 @@ const (
 	AUTH_BASIC = 1
 	AUTH_NTLM  = 2
 
+	// Protocols
+	PROTO_HTTP1 = "http/1.1"
+	PROTO_H2    = "h2"
+	PROTO_H2C   = "h2c"
+	PROTO_H3    = "h3"
+
 	// Exit codes
 	EXIT_OK       = 0
 	EXIT_WARNING  = 1
@@ -41,6 +55,18 @@ type SSLCheck struct {
 	DaysCritical int
 }
 
+// Custom CA bundle, system roots are replaced unless requested
+type CA struct {
+	File        string
+	Dir         string
+	SystemRoots bool
+}
+
+type HeaderCheck struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
 type ClientCert struct {
 	ClientCertFile string
 	PrivateKeyFile string
@@ -54,23 +80,64 @@ type Request struct {
 	TLS              bool
 	Port             int
 	URI              string
-	Timeout          int
+	Timeout          float64
 	Verbose          bool
 	SSLNoVerify      bool
 	Authentication   Authentication
 	FollowRedirects  bool
-	WarningTimeout   int
-	CriticalTimeout  int
+	TimeThreshold    Threshold
+	PhaseThresholds  map[string]Threshold
+	ConnectTimeout   float64
+	TLSTimeout       float64
+	HeaderTimeout    float64
+	BodyTimeout      float64
 	NoSNI            bool
+	AddressFamily    string
+	Resolve          map[string]string
+	DNSServer        string
+	Proxy            Proxy
+	UnixSocket       string
+	Protocol         string
+	HTTP3            bool
 	ClientCert       ClientCert
 	TLSRenegotiation bool
+	CA               CA
+	TLSMinVersion    uint16
+	TLSMaxVersion    uint16
+	CipherSuites     []uint16
+	Method           string
+	Body             []byte
+	ContentType      string
+	Headers          http.Header
 }
 
 // Check params
 type Expected struct {
-	StatusCodes []int
-	BodyText    string
-	SSLCheck    SSLCheck
+	StatusCodes    []int
+	BodyTexts      []string
+	AnyBodyText    bool
+	SSLCheck       SSLCheck
+	Headers        []HeaderCheck
+	NoHeaders      []string
+	IgnoreCase     bool
+	BodyRegex      *regexp.Regexp
+	InvertRegex    bool
+	JSONChecks     []JSONCheck
+	JSONMetrics    []JSONMetric
+	HealthFormat   string
+	XPathChecks    []XPathCheck
+	SelectorChecks []SelectorCheck
+	JSONSchema     *jsonschema.Schema
+	SizeRange      *Range
+	Protocol       string
+}
+
+// Lookup map for exit code names
+var exitLookup = map[int]string{
+	EXIT_OK:       "OK",
+	EXIT_WARNING:  "WARNING",
+	EXIT_CRITICAL: "CRITICAL",
+	EXIT_UNKNOWN:  "UNKNOWN",
 }
 
 // Lookup map for auth type names
@@ -88,12 +155,62 @@ func (r Request) GetURL() string {
 	} else {
 		host = r.Host
 	}
-	return fmt.Sprintf("%s://%s:%s%s", r.Scheme, host, strconv.Itoa(r.Port), r.URI)
+	return fmt.Sprintf("%s://%s%s", r.Scheme, net.JoinHostPort(host, strconv.Itoa(r.Port)), r.URI)
+}
+
+// Host header getter, IPv6 literals are enclosed in brackets
+func (r Request) GetHostHeader() string {
+	if ip := net.ParseIP(r.Host); ip != nil && ip.To4() == nil {
+		return "[" + r.Host + "]"
+	}
+	return r.Host
+}
+
+// HTTP method getter
+func (r Request) GetMethod() string {
+	if len(r.Method) > 0 {
+		return strings.ToUpper(r.Method)
+	}
+	return "GET"
+}
+
+// Returns more severe exit code, CRITICAL > WARNING > UNKNOWN > OK
+func worseExitCode(a int, b int) int {
+	severity := map[int]int{EXIT_OK: 0, EXIT_UNKNOWN: 1, EXIT_WARNING: 2, EXIT_CRITICAL: 3}
+	if severity[b] > severity[a] {
+		return b
+	}
+	return a
+}
+
+// Appends perfdata and long output to the status line
+func formatOutput(msg string, perfData string, longOutput []string) string {
+	if len(longOutput) == 0 {
+		return fmt.Sprintf("%s|%s", msg, perfData)
+	}
+	return fmt.Sprintf("%s|%s\n%s", msg, perfData, strings.Join(longOutput, "\n"))
+}
+
+// Splits check output to status line, perfdata and long output, see formatOutput
+func splitOutput(output string) (string, string, []string) {
+	lines := strings.Split(output, "\n")
+	msg, perfData, _ := strings.Cut(lines[0], "|")
+	return msg, perfData, lines[1:]
+}
+
+// Effective request timeout, there is no need to wait longer than critical response time
+func (r Request) EffectiveTimeout() time.Duration {
+	timeout := r.Timeout
+	critical := r.TimeThreshold.Critical
+	if critical != nil && !critical.Inside && !math.IsInf(critical.End, 1) && (timeout <= 0 || critical.End < timeout) {
+		timeout = critical.End
+	}
+	return secondsDuration(timeout)
 }
 
-// Use timeout interval
-func (r Request) UseTimoutInterval() bool {
-	return r.WarningTimeout > 0 && r.CriticalTimeout > 0 && r.WarningTimeout < r.CriticalTimeout
+// Converts seconds to duration
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
 }
 
 // Status code check helper
@@ -106,6 +223,156 @@ func checkStatusCode(code int, e *Expected) bool {
 	return false
 }
 
+// Response headers check helper
+func checkHeaders(header http.Header, e *Expected) (string, int) {
+	for _, headerCheck := range e.Headers {
+		values := header.Values(headerCheck.Name)
+		if len(values) == 0 {
+			return fmt.Sprintf("CRITICAL - Header '%s' not found in response", headerCheck.Name), EXIT_CRITICAL
+		}
+		if headerCheck.Pattern == nil {
+			continue
+		}
+		matched := false
+		for _, value := range values {
+			if headerCheck.Pattern.MatchString(value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Sprintf("CRITICAL - Header '%s' value '%s' does not match '%s'", headerCheck.Name, strings.Join(values, ", "), headerCheck.Pattern.String()), EXIT_CRITICAL
+		}
+	}
+	for _, name := range e.NoHeaders {
+		if len(header.Values(name)) > 0 {
+			return fmt.Sprintf("CRITICAL - Header '%s' found in response, expected it to be absent", name), EXIT_CRITICAL
+		}
+	}
+	return "", EXIT_OK
+}
+
+// Body check helper
+func checkBody(body []byte, e *Expected) (string, int) {
+	// Body texts
+	if len(e.BodyTexts) > 0 {
+		searchedBody := body
+		if e.IgnoreCase {
+			searchedBody = bytes.ToLower(body)
+		}
+		var missing []string
+		for _, text := range e.BodyTexts {
+			expectedText := []byte(text)
+			if e.IgnoreCase {
+				expectedText = bytes.ToLower(expectedText)
+			}
+			if !bytes.Contains(searchedBody, expectedText) {
+				missing = append(missing, fmt.Sprintf("'%s'", text))
+			}
+		}
+		if e.AnyBodyText && len(missing) == len(e.BodyTexts) {
+			if len(missing) == 1 {
+				return fmt.Sprintf("CRITICAL - String %s not found in body", missing[0]), EXIT_CRITICAL
+			}
+			return fmt.Sprintf("CRITICAL - None of strings %s found in body", strings.Join(missing, ", ")), EXIT_CRITICAL
+		}
+		if !e.AnyBodyText && len(missing) == 1 {
+			return fmt.Sprintf("CRITICAL - String %s not found in body", missing[0]), EXIT_CRITICAL
+		}
+		if !e.AnyBodyText && len(missing) > 1 {
+			return fmt.Sprintf("CRITICAL - Strings %s not found in body", strings.Join(missing, ", ")), EXIT_CRITICAL
+		}
+	}
+
+	// Body regex
+	if e.BodyRegex != nil {
+		found := e.BodyRegex.Match(body)
+		if !found && !e.InvertRegex {
+			return fmt.Sprintf("CRITICAL - Pattern '%s' not found in body", e.BodyRegex.String()), EXIT_CRITICAL
+		}
+		if found && e.InvertRegex {
+			return fmt.Sprintf("CRITICAL - Pattern '%s' found in body", e.BodyRegex.String()), EXIT_CRITICAL
+		}
+	}
+
+	return "", EXIT_OK
+}
+
+// Body size check helper, size out of range is a warning like in check_http
+func checkSize(size int, e *Expected) (string, int) {
+	if e.SizeRange == nil {
+		return "", EXIT_OK
+	}
+	if float64(size) < e.SizeRange.Start {
+		return fmt.Sprintf("WARNING - Page size %dB too small (minimum %sB)", size, strconv.FormatFloat(e.SizeRange.Start, 'f', -1, 64)), EXIT_WARNING
+	}
+	if float64(size) > e.SizeRange.End {
+		return fmt.Sprintf("WARNING - Page size %dB too large (maximum %sB)", size, strconv.FormatFloat(e.SizeRange.End, 'f', -1, 64)), EXIT_WARNING
+	}
+	return "", EXIT_OK
+}
+
+// Negotiated protocol of response ex. http/1.1, h2, h2c, h3
+func responseProtocol(res *http.Response) string {
+	if res.ProtoMajor == 3 {
+		return PROTO_H3
+	}
+	if res.ProtoMajor == 2 {
+		if res.TLS != nil {
+			return PROTO_H2
+		}
+		return PROTO_H2C
+	}
+	return strings.ToLower(res.Proto)
+}
+
+// Protocol check helper
+func checkProtocol(res *http.Response, e *Expected) (string, int) {
+	if len(e.Protocol) == 0 {
+		return "", EXIT_OK
+	}
+	if protocol := responseProtocol(res); protocol != e.Protocol {
+		return fmt.Sprintf("CRITICAL - Got protocol %s, expected %s", protocol, e.Protocol), EXIT_CRITICAL
+	}
+	return "", EXIT_OK
+}
+
+// Maps timeout errors to messages of the phase that timed out
+func checkTimeout(err error, r *Request) (string, bool) {
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		return "", false
+	}
+
+	var opErr *net.OpError
+	switch {
+	case r.ConnectTimeout > 0 && errors.As(err, &opErr) && opErr.Op == "dial":
+		return fmt.Sprintf("CRITICAL - Timeout - Connection not established in %s seconds", formatPerfValue(r.ConnectTimeout)), true
+	case r.TLSTimeout > 0 && strings.Contains(err.Error(), "TLS handshake timeout"):
+		return fmt.Sprintf("CRITICAL - Timeout - TLS handshake not finished in %s seconds", formatPerfValue(r.TLSTimeout)), true
+	case r.HeaderTimeout > 0 && strings.Contains(err.Error(), "timeout awaiting response headers"):
+		return fmt.Sprintf("CRITICAL - Timeout - No response headers received in %s seconds", formatPerfValue(r.HeaderTimeout)), true
+	}
+	return fmt.Sprintf("CRITICAL - Timeout - No response recieved in %s seconds", formatPerfValue(r.EffectiveTimeout().Seconds())), true
+}
+
+// Response time check helper
+func checkResponseTime(seconds float64, r *Request) (string, int) {
+	code := r.TimeThreshold.Evaluate(seconds)
+	if code == EXIT_OK {
+		return "", EXIT_OK
+	}
+
+	threshold := r.TimeThreshold.Warning
+	if code == EXIT_CRITICAL {
+		threshold = r.TimeThreshold.Critical
+	}
+	if threshold.IsUpperLimit() {
+		return fmt.Sprintf("%s - Timeout - No response recieved in %s seconds", exitLookup[code], formatPerfValue(threshold.End)), code
+	}
+	return fmt.Sprintf("%s - Response time %ss violates %s threshold %s", exitLookup[code], formatPerfValue(seconds), strings.ToLower(exitLookup[code]), threshold.Raw), code
+}
+
 // Certificate check helper
 func checkCerts(certs [][]*x509.Certificate, e *Expected) (string, int) {
 	timeNow := time.Now()
@@ -128,6 +395,54 @@ func checkCerts(certs [][]*x509.Certificate, e *Expected) (string, int) {
 	return "", EXIT_OK
 }
 
+// Loads root CAs from PEM file and PEM files in directory
+func loadRootCAs(ca CA) (*x509.CertPool, error) {
+	rootCAs := x509.NewCertPool()
+	if ca.SystemRoots {
+		systemRoots, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, err
+		}
+		rootCAs = systemRoots
+	}
+
+	if len(ca.File) > 0 {
+		pem, err := ioutil.ReadFile(ca.File)
+		if err != nil {
+			return nil, err
+		}
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", ca.File)
+		}
+	}
+
+	if len(ca.Dir) > 0 {
+		files, err := ioutil.ReadDir(ca.Dir)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, file := range files {
+			if file.IsDir() {
+				continue
+			}
+			// Files without certificates ex. hash links to directories or CRLs are skipped
+			pem, err := ioutil.ReadFile(filepath.Join(ca.Dir, file.Name()))
+			if err != nil {
+				continue
+			}
+			if rootCAs.AppendCertsFromPEM(pem) {
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no certificates found in CA directory %s", ca.Dir)
+		}
+	}
+
+	return rootCAs, nil
+}
+
 // TLS config factory
 func getTLSConfig(r *Request) (*tls.Config, error) {
 	TLSConfig := &tls.Config{}
@@ -142,6 +457,15 @@ func getTLSConfig(r *Request) (*tls.Config, error) {
 		TLSConfig.ServerName = r.Host
 	}
 
+	// Custom CA bundle
+	if len(r.CA.File) > 0 || len(r.CA.Dir) > 0 {
+		rootCAs, err := loadRootCAs(r.CA)
+		if err != nil {
+			return nil, err
+		}
+		TLSConfig.RootCAs = rootCAs
+	}
+
 	// Client cert
 	if r.ClientCert.ClientCertFile != "" && r.ClientCert.PrivateKeyFile != "" {
 		cert, err := tls.LoadX509KeyPair(r.ClientCert.ClientCertFile, r.ClientCert.PrivateKeyFile)
@@ -156,9 +480,34 @@ func getTLSConfig(r *Request) (*tls.Config, error) {
 		TLSConfig.Renegotiation = tls.RenegotiateOnceAsClient
 	}
 
+	// Protocol versions and cipher suites, cipher suites are not configurable in TLS 1.3
+	TLSConfig.MinVersion = r.TLSMinVersion
+	TLSConfig.MaxVersion = r.TLSMaxVersion
+	TLSConfig.CipherSuites = r.CipherSuites
+
 	return TLSConfig, nil
 }
 
+// Dial function factory, respects unix socket, address family, resolve entries and DNS server
+func newDialFunc(r *Request) dialFunc {
+	dialer := &net.Dialer{
+		Timeout:   secondsDuration(r.ConnectTimeout),
+		KeepAlive: 30 * time.Second,
+		Resolver:  newResolver(r),
+	}
+	return func(ctx context.Context, network string, address string) (net.Conn, error) {
+		// Unix socket replaces every address
+		if len(r.UnixSocket) > 0 {
+			return dialer.DialContext(ctx, "unix", r.UnixSocket)
+		}
+		// Force address family ex. tcp4 or tcp6
+		if len(r.AddressFamily) > 0 {
+			network = r.AddressFamily
+		}
+		return dialer.DialContext(ctx, network, resolveAddress(r, address))
+	}
+}
+
 // HTTP client factory
 func initHTTPClient(r *Request) (*http.Client, error) {
 	// Get TLS config
@@ -167,19 +516,38 @@ func initHTTPClient(r *Request) (*http.Client, error) {
 		return nil, err
 	}
 
-	http.DefaultTransport.(*http.Transport).TLSClientConfig = TLSConfig
-
-	// Setup timeout
-	var timeout time.Duration
-	if r.UseTimoutInterval() {
-		timeout = time.Duration(time.Duration(r.CriticalTimeout) * time.Second)
-	} else {
-		timeout = time.Duration(time.Duration(r.Timeout) * time.Second)
+	// Init transport, phase timeouts override defaults
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = TLSConfig
+	dial := newDialFunc(r)
+	transport.DialContext = dial
+	if r.TLSTimeout > 0 {
+		transport.TLSHandshakeTimeout = secondsDuration(r.TLSTimeout)
+	}
+	transport.ResponseHeaderTimeout = secondsDuration(r.HeaderTimeout)
+
+	// Force protocol, HTTP/1.1 and HTTP/2 over TLS are negotiated by default
+	if len(r.Protocol) > 0 {
+		var protocols http.Protocols
+		switch r.Protocol {
+		case PROTO_HTTP1:
+			protocols.SetHTTP1(true)
+		case PROTO_H2:
+			protocols.SetHTTP2(true)
+		case PROTO_H2C:
+			protocols.SetUnencryptedHTTP2(true)
+		}
+		transport.Protocols = &protocols
 	}
 
-	// Init client
+	// Init client, proxy is configured last as it may clone transport, HTTP/3 replaces TCP transport
+	roundTripper := setProxy(transport, r, dial)
+	if r.HTTP3 {
+		roundTripper = newHTTP3Transport(r, TLSConfig)
+	}
 	client := &http.Client{
-		Timeout: timeout,
+		Transport: roundTripper,
+		Timeout:   r.EffectiveTimeout(),
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			if r.FollowRedirects {
 				return nil
@@ -192,11 +560,75 @@ func initHTTPClient(r *Request) (*http.Client, error) {
 	return client, nil
 }
 
+// Configures request authentication, NTLM negotiation wraps client transport
+func setAuthentication(client *http.Client, request *http.Request, auth Authentication) {
+	if auth.Type == AUTH_BASIC {
+		request.SetBasicAuth(auth.User, auth.Password)
+	}
+
+	// TODO - test
+	if auth.Type == AUTH_NTLM {
+		transport := ntlmssp.Negotiator{
+			RoundTripper: client.Transport,
+		}
+		client.Transport = transport
+		request.SetBasicAuth(auth.User, auth.Password)
+	}
+}
+
 // Adds custom User-Agent header
 func setUserAgent(request *http.Request) {
 	request.Header.Set("User-Agent", fmt.Sprintf("icinga-http-check/%s Go-http-client/%s", appVersion, goVersion))
 }
 
+// Adds custom request headers, Host header overrides request host
+func setHeaders(request *http.Request, headers http.Header) {
+	for name, values := range headers {
+		if http.CanonicalHeaderKey(name) == "Host" {
+			request.Host = values[len(values)-1]
+			continue
+		}
+		request.Header.Del(name)
+		for _, value := range values {
+			request.Header.Add(name, value)
+		}
+	}
+}
+
+// HTTP request factory
+func newHTTPRequest(r *Request) (*http.Request, error) {
+	var body io.Reader
+	if r.Body != nil {
+		body = bytes.NewReader(r.Body)
+	}
+
+	request, err := http.NewRequest(r.GetMethod(), r.GetURL(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	// User agent
+	setUserAgent(request)
+
+	// Content type
+	if r.Body != nil && len(r.ContentType) > 0 {
+		request.Header.Set("Content-Type", r.ContentType)
+	}
+
+	// SNI, proxy builds forwarded request URI from Host header so non-default port is kept
+	if !r.NoSNI && len(r.Host) > 0 {
+		request.Host = r.GetHostHeader()
+		if (r.Proxy.URL != nil || r.Proxy.FromEnvironment) && r.Scheme == "http" && r.Port != 80 {
+			request.Host = net.JoinHostPort(r.Host, strconv.Itoa(r.Port))
+		}
+	}
+
+	// Custom headers
+	setHeaders(request, r.Headers)
+
+	return request, nil
+}
+
 // Main check function
 func Check(r *Request, e *Expected) (string, int, error) {
 	if len(r.Host) == 0 && len(r.IPAddress) == 0 {
@@ -207,15 +639,17 @@ func Check(r *Request, e *Expected) (string, int, error) {
 	if err != nil {
 		return "CRITICAL", EXIT_CRITICAL, err
 	}
+	defer client.CloseIdleConnections()
 
 	url := r.GetURL()
 
 	if r.Verbose {
 		fmt.Println(">> URL: " + url)
+		fmt.Println(">> Method: " + r.GetMethod())
 	}
 
 	// Prepare request
-	request, err := http.NewRequest("GET", url, nil)
+	request, err := newHTTPRequest(r)
 	if err != nil {
 		if r.Verbose {
 			fmt.Println(fmt.Sprintf(">> http.NewRequest error: %v", err))
@@ -224,68 +658,87 @@ func Check(r *Request, e *Expected) (string, int, error) {
 		return "UNKNOWN", EXIT_UNKNOWN, err
 	}
 
-	// User agent
-	setUserAgent(request)
-
 	// Authentication
-	if r.Authentication.Type == AUTH_BASIC {
-		request.SetBasicAuth(r.Authentication.User, r.Authentication.Password)
-	}
-
-	// TODO - test
-	if r.Authentication.Type == AUTH_NTLM {
-		// Get TLS config
-		TLSConfig, err := getTLSConfig(r)
-		if err != nil {
-			return "CRITICAL", EXIT_CRITICAL, err
-		}
-		transport := ntlmssp.Negotiator{
-			RoundTripper: &http.Transport{
-				TLSClientConfig: TLSConfig,
-			},
-		}
-		client.Transport = transport
-		request.SetBasicAuth(r.Authentication.User, r.Authentication.Password)
-	}
+	setAuthentication(client, request, r.Authentication)
 
-	// SNI
-	if !r.NoSNI && len(r.Host) > 0 {
-		request.Host = r.Host
-	}
+	// Trace request phases
+	timing := NewTiming()
+	ctx, cancel := context.WithCancel(httptrace.WithClientTrace(request.Context(), timing.ClientTrace()))
+	defer cancel()
+	request = request.WithContext(ctx)
 
 	start := time.Now()
-	timeInfo := func() string {
-		return fmt.Sprintf("time=%fs", float32(time.Now().UnixNano()-start.UnixNano())/float32(1000000000))
+	var perfData PerfDataList
+	perfInfo := func() string {
+		timePerfData := PerfData{
+			Label:     "time",
+			Value:     time.Since(start).Seconds(),
+			UOM:       "s",
+			Threshold: r.TimeThreshold,
+			Min:       perfBound(0),
+			Max:       perfBound(r.EffectiveTimeout().Seconds()),
+		}
+		phasesPerfData := timing.PerfData(r)
+		return append(append(PerfDataList{timePerfData}, phasesPerfData...), perfData...).String()
 	}
 	res, err := client.Do(request)
 	if err != nil {
 		if r.Verbose {
-			fmt.Println(fmt.Sprintf(">> client.GET error: %v", err))
+			fmt.Println(fmt.Sprintf(">> client.Do error: %v", err))
 		}
-		if err, ok := err.(net.Error); ok && err.Timeout() {
-			var timeout int
-			if r.UseTimoutInterval() {
-				timeout = r.CriticalTimeout
-			} else {
-				timeout = r.Timeout
-			}
-			return fmt.Sprintf("CRITICAL - Timeout - No response recieved in %d seconds|%s", timeout, timeInfo()), EXIT_CRITICAL, nil
+		if timeoutMsg, ok := checkTimeout(err, r); ok {
+			return fmt.Sprintf("%s|%s", timeoutMsg, perfInfo()), EXIT_CRITICAL, nil
 		}
-		return fmt.Sprintf("CRITICAL - %s|%s", err.Error(), timeInfo()), EXIT_CRITICAL, nil
+		return fmt.Sprintf("CRITICAL - %s|%s", err.Error(), perfInfo()), EXIT_CRITICAL, nil
 	}
 
 	defer res.Body.Close()
 
-	// Timeout interval
-	if r.UseTimoutInterval() {
-		delta := float32(time.Now().UnixNano()-start.UnixNano()) / float32(1000000000)
-		if delta >= float32(r.WarningTimeout) {
-			return fmt.Sprintf("WARNING - Timeout - No response recieved in %d seconds|%s", r.WarningTimeout, timeInfo()), EXIT_WARNING, nil
+	// Read body, body timeout starts with response headers
+	var bodyTimer *time.Timer
+	if r.BodyTimeout > 0 {
+		bodyTimer = time.AfterFunc(secondsDuration(r.BodyTimeout), cancel)
+	}
+	bodyBytes, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		if r.Verbose {
+			fmt.Println(fmt.Sprintf(">> Body read error: %v", err))
 		}
+		if bodyTimer != nil && !bodyTimer.Stop() {
+			return fmt.Sprintf("CRITICAL - Timeout - Body not received in %s seconds|%s", formatPerfValue(r.BodyTimeout), perfInfo()), EXIT_CRITICAL, nil
+		}
+		if timeoutMsg, ok := checkTimeout(err, r); ok {
+			return fmt.Sprintf("%s|%s", timeoutMsg, perfInfo()), EXIT_CRITICAL, nil
+		}
+		return "UNKNOWN", EXIT_UNKNOWN, err
+	}
+	if bodyTimer != nil {
+		bodyTimer.Stop()
+	}
+	timing.BodyRead()
+	perfData = append(perfData, PerfData{
+		Label:     "size",
+		Value:     float64(len(bodyBytes)),
+		UOM:       "B",
+		Threshold: Threshold{Warning: e.SizeRange},
+		Min:       perfBound(0),
+	})
+	if res.TLS != nil {
+		perfData = append(perfData, tlsPerfData(res.TLS)...)
 	}
 
 	if r.Verbose {
 		fmt.Println(fmt.Sprintf(">> Response status: %s", res.Status))
+		fmt.Println(fmt.Sprintf(">> Response protocol: %s", responseProtocol(res)))
+		if res.TLS != nil {
+			fmt.Println(fmt.Sprintf(">> TLS: %s", describeTLS(res.TLS)))
+		}
+	}
+
+	// Response time thresholds, including body transfer
+	timeMsg, timeExit := checkResponseTime(time.Since(start).Seconds(), r)
+	if timeExit != EXIT_OK {
+		return fmt.Sprintf("%s|%s", timeMsg, perfInfo()), timeExit, nil
 	}
 
 	// Check status code
@@ -294,30 +747,108 @@ func Check(r *Request, e *Expected) (string, int, error) {
 		for _, code := range e.StatusCodes {
 			expectedStatusCodes = append(expectedStatusCodes, strconv.Itoa(code))
 		}
-		return fmt.Sprintf("CRITICAL - Got  response HTTP/1.1 %s, expected %s|%s", strconv.Itoa(res.StatusCode), strings.Join(expectedStatusCodes, ", "), timeInfo()), EXIT_CRITICAL, nil
+		// Health endpoints report failing components with 5xx status codes
+		if len(e.HealthFormat) > 0 {
+			if health, err := parseHealthBody(bodyBytes, e.HealthFormat, res.Header.Get("Content-Type")); err == nil {
+				healthMsg, healthExit, longOutput := checkHealth(health)
+				if healthExit != EXIT_OK {
+					return formatOutput(healthMsg, perfInfo(), longOutput), healthExit, nil
+				}
+			}
+		}
+		return fmt.Sprintf("CRITICAL - Got  response %s %s, expected %s|%s", res.Proto, strconv.Itoa(res.StatusCode), strings.Join(expectedStatusCodes, ", "), perfInfo()), EXIT_CRITICAL, nil
+	}
+
+	// Check negotiated protocol
+	protocolMsg, protocolExit := checkProtocol(res, e)
+	if protocolExit != EXIT_OK {
+		return fmt.Sprintf("%s|%s", protocolMsg, perfInfo()), protocolExit, nil
+	}
+
+	// Check response headers
+	headersMsg, headersExit := checkHeaders(res.Header, e)
+	if headersExit != EXIT_OK {
+		return fmt.Sprintf("%s|%s", headersMsg, perfInfo()), headersExit, nil
+	}
+
+	// Check request phases
+	phasesMsg, phasesExit := checkPhases(timing, r)
+	if phasesExit != EXIT_OK {
+		return fmt.Sprintf("%s|%s", phasesMsg, perfInfo()), phasesExit, nil
+	}
+
+	// Check body
+	bodyMsg, bodyExit := checkBody(bodyBytes, e)
+	if bodyExit != EXIT_OK {
+		return fmt.Sprintf("%s|%s", bodyMsg, perfInfo()), bodyExit, nil
+	}
+
+	// Check JSON
+	if len(e.JSONChecks) > 0 || len(e.JSONMetrics) > 0 || e.JSONSchema != nil {
+		document, err := decodeJSON(bodyBytes)
+		if err != nil {
+			return fmt.Sprintf("CRITICAL - Response body is not valid JSON: %s|%s", err.Error(), perfInfo()), EXIT_CRITICAL, nil
+		}
+		if e.JSONSchema != nil {
+			schemaMsg, schemaExit := checkJSONSchema(document, e)
+			if schemaExit != EXIT_OK {
+				return fmt.Sprintf("%s|%s", schemaMsg, perfInfo()), schemaExit, nil
+			}
+		}
+		metricsMsg, metricsExit, metricsPerfData := checkJSONMetrics(document, e)
+		perfData = append(perfData, metricsPerfData...)
+		JSONMsg, JSONExit := checkJSON(document, e)
+		if JSONExit != EXIT_OK {
+			return fmt.Sprintf("%s|%s", JSONMsg, perfInfo()), JSONExit, nil
+		}
+		if metricsExit != EXIT_OK {
+			return fmt.Sprintf("%s|%s", metricsMsg, perfInfo()), metricsExit, nil
+		}
 	}
 
-	// Check body text
-	if len(e.BodyText) > 0 {
-		expectedText := []byte(e.BodyText)
-		bodyBytes, err := ioutil.ReadAll(res.Body)
+	// Check XPath
+	if len(e.XPathChecks) > 0 {
+		XPathMsg, XPathExit := checkXPath(bodyBytes, e)
+		if XPathExit != EXIT_OK {
+			return fmt.Sprintf("%s|%s", XPathMsg, perfInfo()), XPathExit, nil
+		}
+	}
+
+	// Check CSS selectors
+	if len(e.SelectorChecks) > 0 {
+		selectorsMsg, selectorsExit := checkSelectors(bodyBytes, e)
+		if selectorsExit != EXIT_OK {
+			return fmt.Sprintf("%s|%s", selectorsMsg, perfInfo()), selectorsExit, nil
+		}
+	}
+
+	// Check health
+	if len(e.HealthFormat) > 0 {
+		health, err := parseHealthBody(bodyBytes, e.HealthFormat, res.Header.Get("Content-Type"))
 		if err != nil {
-			return "UNKNOWN", EXIT_UNKNOWN, err
+			return fmt.Sprintf("CRITICAL - Response body is not a health response: %s|%s", err.Error(), perfInfo()), EXIT_CRITICAL, nil
 		}
-		if !bytes.Contains(bodyBytes, expectedText) {
-			return fmt.Sprintf("CRITICAL - String '%s' not found in body|%s", e.BodyText, timeInfo()), EXIT_CRITICAL, nil
+		healthMsg, healthExit, longOutput := checkHealth(health)
+		if healthExit != EXIT_OK {
+			return formatOutput(healthMsg, perfInfo(), longOutput), healthExit, nil
 		}
 	}
 
+	// Check body size
+	sizeMsg, sizeExit := checkSize(len(bodyBytes), e)
+	if sizeExit != EXIT_OK {
+		return fmt.Sprintf("%s|%s", sizeMsg, perfInfo()), sizeExit, nil
+	}
+
 	// Check SSL cert
 	if e.SSLCheck.Run {
 		SSLMsg, SSLExit := checkCerts(res.TLS.VerifiedChains, e)
 		if SSLExit != EXIT_OK {
-			return fmt.Sprintf("%s|%s", SSLMsg, timeInfo()), SSLExit, nil
+			return fmt.Sprintf("%s|%s", SSLMsg, perfInfo()), SSLExit, nil
 		}
 	}
 
-	return fmt.Sprintf("OK - Got response HTTP/1.1 %s|%s", strconv.Itoa(res.StatusCode), timeInfo()), EXIT_OK, nil
+	return fmt.Sprintf("OK - Got response %s %s|%s", res.Proto, strconv.Itoa(res.StatusCode), perfInfo()), EXIT_OK, nil
 }
 
 // Detects auth type
@@ -328,22 +859,16 @@ func DetectAuthType(r *Request) int {
 		return AUTH_NONE
 	}
 
-	url := r.GetURL()
-
-	request, err := http.NewRequest("GET", url, nil)
+	// Request body is not sent, detection must not repeat POST or PUT requests
+	detectRequest := *r
+	detectRequest.Method = "GET"
+	detectRequest.Body = nil
+	request, err := newHTTPRequest(&detectRequest)
 	if err != nil {
 		// `Check` should handle all errors
 		return AUTH_NONE
 	}
 
-	// User agent
-	setUserAgent(request)
-
-	// SNI
-	if !r.NoSNI && len(r.Host) > 0 {
-		request.Host = r.Host
-	}
-
 	res, err := client.Do(request)
 	if err != nil {
 		// `Check` should handle all errors
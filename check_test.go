@@ -2,11 +2,34 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
+// Builds request pointing to the local test server
+func newTestRequest(ts *httptest.Server, uri string) *Request {
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+	return &Request{
+		Scheme:  u.Scheme,
+		Host:    u.Hostname(),
+		Port:    port,
+		URI:     uri,
+		Timeout: 30,
+		Verbose: false,
+	}
+}
+
 func TestHTTPCodes(t *testing.T) {
 	statusCodes := [4]int{200, 302, 404, 500}
 	for _, statusCode := range statusCodes {
@@ -111,14 +134,14 @@ func TestTimeout(t *testing.T) {
 }
 
 func TestTimeoutWarning(t *testing.T) {
+	timeThreshold, _ := ParseTimeThreshold("5", "15")
 	r := &Request{
-		Scheme:          "https",
-		Host:            "httpbin.org",
-		Port:            443,
-		URI:             "/delay/10",
-		WarningTimeout:  5,
-		CriticalTimeout: 15,
-		Verbose:         false,
+		Scheme:        "https",
+		Host:          "httpbin.org",
+		Port:          443,
+		URI:           "/delay/10",
+		TimeThreshold: timeThreshold,
+		Verbose:       false,
 	}
 
 	var currrentStatusCodes []int
@@ -147,14 +170,14 @@ func TestTimeoutWarning(t *testing.T) {
 }
 
 func TestTimeoutCritical(t *testing.T) {
+	timeThreshold, _ := ParseTimeThreshold("4", "8")
 	r := &Request{
-		Scheme:          "https",
-		Host:            "httpbin.org",
-		Port:            443,
-		URI:             "/delay/10",
-		WarningTimeout:  4,
-		CriticalTimeout: 8,
-		Verbose:         false,
+		Scheme:        "https",
+		Host:          "httpbin.org",
+		Port:          443,
+		URI:           "/delay/10",
+		TimeThreshold: timeThreshold,
+		Verbose:       false,
 	}
 
 	var currrentStatusCodes []int
@@ -268,7 +291,7 @@ func TestContainsTextOK(t *testing.T) {
 	currrentStatusCodes = append(currrentStatusCodes, 200)
 	e := &Expected{
 		StatusCodes: currrentStatusCodes,
-		BodyText:    "foobar",
+		BodyTexts:   []string{"foobar"},
 	}
 
 	msg, code, err := Check(r, e)
@@ -300,7 +323,7 @@ func TestContainsTextFail(t *testing.T) {
 	currrentStatusCodes = append(currrentStatusCodes, 200)
 	e := &Expected{
 		StatusCodes: currrentStatusCodes,
-		BodyText:    "loremipsum",
+		BodyTexts:   []string{"loremipsum"},
 	}
 
 	msg, code, err := Check(r, e)
@@ -429,6 +452,30 @@ func TestNoneAuthDetect(t *testing.T) {
 	}
 }
 
+func TestAuthDetectWithoutBody(t *testing.T) {
+	var methods []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		methods = append(methods, fmt.Sprintf("%s %d", req.Method, len(body)))
+		w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	r := newTestRequest(ts, "/")
+	r.Method = "POST"
+	r.Body = []byte("foo=bar")
+
+	authCode := DetectAuthType(r)
+
+	if authCode != AUTH_BASIC {
+		t.Errorf("Basic auth - wrong auth type detected")
+	}
+	if len(methods) != 1 || methods[0] != "GET 0" {
+		t.Errorf("Wrong detection request %v", methods)
+	}
+}
+
 func TestUserAgent(t *testing.T) {
 	r := &Request{
 		Scheme:  "https",
@@ -443,7 +490,7 @@ func TestUserAgent(t *testing.T) {
 	currrentStatusCodes = append(currrentStatusCodes, 200)
 	e := &Expected{
 		StatusCodes: currrentStatusCodes,
-		BodyText:    fmt.Sprintf("icinga-http-check/%s Go-http-client/%s", appVersion, goVersion),
+		BodyTexts:   []string{fmt.Sprintf("icinga-http-check/%s Go-http-client/%s", appVersion, goVersion)},
 	}
 
 	msg, code, err := Check(r, e)
@@ -476,7 +523,7 @@ func TestFollowRedirects(t *testing.T) {
 	currrentStatusCodes = append(currrentStatusCodes, 200)
 	e := &Expected{
 		StatusCodes: currrentStatusCodes,
-		BodyText:    fmt.Sprintf("icinga-http-check/%s Go-http-client/%s", appVersion, goVersion),
+		BodyTexts:   []string{fmt.Sprintf("icinga-http-check/%s Go-http-client/%s", appVersion, goVersion)},
 	}
 
 	msg, code, err := Check(r, e)
@@ -493,3 +540,629 @@ func TestFollowRedirects(t *testing.T) {
 		t.Errorf("Returned error is not nil [URI: %s]", r.URI)
 	}
 }
+
+func TestMethodAndBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		fmt.Fprintf(w, "%s %s %s", req.Method, req.Header.Get("Content-Type"), body)
+	}))
+	defer ts.Close()
+
+	r := newTestRequest(ts, "/api")
+	r.Method = "put"
+	r.Body = []byte(`{"status": "UP"}`)
+	r.ContentType = "application/json"
+
+	e := &Expected{
+		StatusCodes: []int{200},
+		BodyTexts:   []string{`PUT application/json {"status": "UP"}`},
+	}
+
+	msg, code, err := Check(r, e)
+
+	if !strings.HasPrefix(msg, "OK") {
+		t.Errorf("Wrong message [URI: %s]: %s", r.URI, msg)
+	}
+
+	if code != EXIT_OK {
+		t.Errorf("Wrong exit code [URI: %s]", r.URI)
+	}
+
+	if err != nil {
+		t.Errorf("Returned error is not nil [URI: %s]", r.URI)
+	}
+}
+
+func TestDefaultMethod(t *testing.T) {
+	r := &Request{}
+	if r.GetMethod() != "GET" {
+		t.Errorf("Wrong default method: %s", r.GetMethod())
+	}
+}
+
+func TestCustomHeaders(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "%s|%s|%s|%s", req.Host, req.Header.Get("X-Api-Key"), req.Header.Get("Accept"), req.UserAgent())
+	}))
+	defer ts.Close()
+
+	r := newTestRequest(ts, "/")
+	r.Headers = http.Header{}
+	r.Headers.Add("x-api-key", "secret")
+	r.Headers.Add("Accept", "application/json")
+	r.Headers.Add("User-Agent", "custom-agent")
+	r.Headers.Add("Host", "example.com")
+
+	e := &Expected{
+		StatusCodes: []int{200},
+		BodyTexts:   []string{"example.com|secret|application/json|custom-agent"},
+	}
+
+	msg, code, err := Check(r, e)
+
+	if !strings.HasPrefix(msg, "OK") {
+		t.Errorf("Wrong message [URI: %s]: %s", r.URI, msg)
+	}
+
+	if code != EXIT_OK {
+		t.Errorf("Wrong exit code [URI: %s]", r.URI)
+	}
+
+	if err != nil {
+		t.Errorf("Returned error is not nil [URI: %s]", r.URI)
+	}
+}
+
+func TestExpectedHeaders(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		w.Header().Set("X-Powered-By", "PHP/5.6")
+		fmt.Fprint(w, "OK")
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		headers   []HeaderCheck
+		noHeaders []string
+		code      int
+		msg       string
+	}{
+		{[]HeaderCheck{{Name: "cache-control", Pattern: regexp.MustCompile(`max-age=\d+`)}}, nil, EXIT_OK, "OK"},
+		{[]HeaderCheck{{Name: "Cache-Control"}}, nil, EXIT_OK, "OK"},
+		{[]HeaderCheck{{Name: "Cache-Control", Pattern: regexp.MustCompile(`no-cache`)}}, nil, EXIT_CRITICAL, "CRITICAL - Header 'Cache-Control' value"},
+		{[]HeaderCheck{{Name: "Strict-Transport-Security"}}, nil, EXIT_CRITICAL, "CRITICAL - Header 'Strict-Transport-Security' not found"},
+		{nil, []string{"X-Frame-Options"}, EXIT_OK, "OK"},
+		{nil, []string{"X-Powered-By"}, EXIT_CRITICAL, "CRITICAL - Header 'X-Powered-By' found"},
+	}
+
+	for _, test := range tests {
+		r := newTestRequest(ts, "/")
+		e := &Expected{
+			StatusCodes: []int{200},
+			Headers:     test.headers,
+			NoHeaders:   test.noHeaders,
+		}
+
+		msg, code, err := Check(r, e)
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message: %s", msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code: %d", code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil")
+		}
+	}
+}
+
+func TestBodyMatching(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, "<h1>Status: Operational</h1>")
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		expected *Expected
+		code     int
+		msg      string
+	}{
+		{&Expected{BodyTexts: []string{"status: operational"}}, EXIT_CRITICAL, "CRITICAL - String"},
+		{&Expected{BodyTexts: []string{"status: operational"}, IgnoreCase: true}, EXIT_OK, "OK"},
+		{&Expected{BodyRegex: regexp.MustCompile(`Status: \w+`)}, EXIT_OK, "OK"},
+		{&Expected{BodyRegex: regexp.MustCompile(`(?i)status: down`)}, EXIT_CRITICAL, "CRITICAL - Pattern '(?i)status: down' not found"},
+		{&Expected{BodyRegex: regexp.MustCompile(`(?i)fatal error|exception`), InvertRegex: true}, EXIT_OK, "OK"},
+		{&Expected{BodyRegex: regexp.MustCompile(`(?i)operational`), InvertRegex: true}, EXIT_CRITICAL, "CRITICAL - Pattern '(?i)operational' found"},
+	}
+
+	for _, test := range tests {
+		r := newTestRequest(ts, "/")
+		test.expected.StatusCodes = []int{200}
+
+		msg, code, err := Check(r, test.expected)
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message: %s", msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code: %d", code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil")
+		}
+	}
+}
+
+func TestMultipleBodyTexts(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, "<h1>Welcome</h1><footer>backend-1</footer>")
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		expected *Expected
+		code     int
+		msg      string
+	}{
+		{&Expected{BodyTexts: []string{"Welcome", "backend-1"}}, EXIT_OK, "OK"},
+		{&Expected{BodyTexts: []string{"Welcome", "backend-2"}}, EXIT_CRITICAL, "CRITICAL - String 'backend-2' not found in body"},
+		{&Expected{BodyTexts: []string{"Hello", "Welcome", "backend-2"}}, EXIT_CRITICAL, "CRITICAL - Strings 'Hello', 'backend-2' not found in body"},
+		{&Expected{BodyTexts: []string{"backend-1", "backend-2"}, AnyBodyText: true}, EXIT_OK, "OK"},
+		{&Expected{BodyTexts: []string{"backend-2", "backend-3"}, AnyBodyText: true}, EXIT_CRITICAL, "CRITICAL - None of strings 'backend-2', 'backend-3' found in body"},
+	}
+
+	for _, test := range tests {
+		r := newTestRequest(ts, "/")
+		test.expected.StatusCodes = []int{200}
+
+		msg, code, err := Check(r, test.expected)
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message: %s", msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code: %d", code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil")
+		}
+	}
+}
+
+func TestPageSize(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, strings.Repeat("x", 1000))
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		sizeRange *Range
+		code      int
+		msg       string
+		perfData  string
+	}{
+		{nil, EXIT_OK, "OK", "size=1000B;;;0"},
+		{&Range{Raw: "512:", Start: 512, End: math.Inf(1)}, EXIT_OK, "OK", "size=1000B;512:;;0"},
+		{&Range{Raw: "2000:", Start: 2000, End: math.Inf(1)}, EXIT_WARNING, "WARNING - Page size 1000B too small (minimum 2000B)", "size=1000B;2000:;;0"},
+		{&Range{Raw: "10:500", Start: 10, End: 500}, EXIT_WARNING, "WARNING - Page size 1000B too large (maximum 500B)", "size=1000B;10:500;;0"},
+	}
+
+	for _, test := range tests {
+		r := newTestRequest(ts, "/")
+		e := &Expected{
+			StatusCodes: []int{200},
+			SizeRange:   test.sizeRange,
+		}
+
+		msg, code, err := Check(r, e)
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message: %s", msg)
+		}
+
+		if !strings.HasSuffix(msg, " "+test.perfData) {
+			t.Errorf("Wrong perfdata: %s", msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code: %d", code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil")
+		}
+	}
+}
+
+func TestResponseTimeThresholds(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprint(w, "OK")
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		warning  string
+		critical string
+		code     int
+		msg      string
+	}{
+		{"1", "2", EXIT_OK, "OK"},
+		{"100ms", "1s", EXIT_WARNING, "WARNING - Timeout - No response recieved in 0.1 seconds"},
+		{"50ms", "0.15", EXIT_CRITICAL, "CRITICAL - Timeout - No response recieved in 0.15 seconds"},
+		{"", "@100ms:1", EXIT_CRITICAL, "CRITICAL - Response time "},
+		{"1:", "", EXIT_WARNING, "WARNING - Response time "},
+	}
+
+	for _, test := range tests {
+		timeThreshold, err := ParseTimeThreshold(test.warning, test.critical)
+		if err != nil {
+			t.Fatalf("Unexpected error [-w %s -c %s]: %v", test.warning, test.critical, err)
+		}
+
+		r := newTestRequest(ts, "/")
+		r.TimeThreshold = timeThreshold
+		e := &Expected{
+			StatusCodes: []int{200},
+		}
+
+		msg, code, err := Check(r, e)
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message [-w %s -c %s]: %s", test.warning, test.critical, msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code [-w %s -c %s]: %d", test.warning, test.critical, code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil [-w %s -c %s]", test.warning, test.critical)
+		}
+	}
+}
+
+func TestPhaseTimeouts(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/slow-headers" {
+			time.Sleep(300 * time.Millisecond)
+		}
+		w.WriteHeader(200)
+		w.(http.Flusher).Flush()
+		time.Sleep(300 * time.Millisecond)
+		fmt.Fprint(w, "OK")
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		uri           string
+		headerTimeout float64
+		bodyTimeout   float64
+		code          int
+		msg           string
+	}{
+		{"/slow-headers", 0.1, 0, EXIT_CRITICAL, "CRITICAL - Timeout - No response headers received in 0.1 seconds"},
+		{"/", 0.1, 0, EXIT_OK, "OK"},
+		{"/", 0, 0.1, EXIT_CRITICAL, "CRITICAL - Timeout - Body not received in 0.1 seconds"},
+		{"/", 0, 1, EXIT_OK, "OK"},
+	}
+
+	for _, test := range tests {
+		r := newTestRequest(ts, test.uri)
+		r.HeaderTimeout = test.headerTimeout
+		r.BodyTimeout = test.bodyTimeout
+		e := &Expected{
+			StatusCodes: []int{200},
+		}
+
+		msg, code, err := Check(r, e)
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message [%s]: %s", test.uri, msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code [%s]: %d", test.uri, code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil [%s]: %v", test.uri, err)
+		}
+	}
+}
+
+func TestTLSTimeout(t *testing.T) {
+	// Accepts connections but never answers the handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	r := &Request{
+		Scheme:     "https",
+		Host:       "127.0.0.1",
+		Port:       listener.Addr().(*net.TCPAddr).Port,
+		URI:        "/",
+		Timeout:    30,
+		TLSTimeout: 0.1,
+	}
+	e := &Expected{
+		StatusCodes: []int{200},
+	}
+
+	msg, code, err := Check(r, e)
+
+	if !strings.HasPrefix(msg, "CRITICAL - Timeout - TLS handshake not finished in 0.1 seconds") {
+		t.Errorf("Wrong message: %s", msg)
+	}
+
+	if code != EXIT_CRITICAL {
+		t.Errorf("Wrong exit code: %d", code)
+	}
+
+	if err != nil {
+		t.Errorf("Returned error is not nil")
+	}
+}
+
+func TestResponseTimeIncludesBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(200)
+		w.(http.Flusher).Flush()
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprint(w, "OK")
+	}))
+	defer ts.Close()
+
+	timeThreshold, _ := ParseTimeThreshold("100ms", "")
+	r := newTestRequest(ts, "/")
+	r.TimeThreshold = timeThreshold
+	e := &Expected{
+		StatusCodes: []int{200},
+	}
+
+	msg, code, err := Check(r, e)
+
+	if !strings.HasPrefix(msg, "WARNING - Timeout - No response recieved in 0.1 seconds") {
+		t.Errorf("Wrong message: %s", msg)
+	}
+
+	if code != EXIT_WARNING {
+		t.Errorf("Wrong exit code: %d", code)
+	}
+
+	if err != nil {
+		t.Errorf("Returned error is not nil")
+	}
+}
+
+func TestGetURL(t *testing.T) {
+	tests := []struct {
+		r   Request
+		url string
+	}{
+		{Request{Scheme: "http", Host: "example.com", Port: 80, URI: "/"}, "http://example.com:80/"},
+		{Request{Scheme: "https", Host: "example.com", IPAddress: "192.0.2.1", Port: 443, URI: "/health"}, "https://192.0.2.1:443/health"},
+		{Request{Scheme: "http", Host: "2001:db8::1", Port: 8080, URI: "/"}, "http://[2001:db8::1]:8080/"},
+		{Request{Scheme: "https", Host: "example.com", IPAddress: "::1", Port: 443, URI: "/"}, "https://[::1]:443/"},
+	}
+
+	for _, test := range tests {
+		if url := test.r.GetURL(); url != test.url {
+			t.Errorf("Wrong URL: %s, expected %s", url, test.url)
+		}
+	}
+
+	hostHeaders := []struct {
+		host   string
+		header string
+	}{
+		{"2001:db8::1", "[2001:db8::1]"},
+		{"192.0.2.1", "192.0.2.1"},
+		{"example.com", "example.com"},
+		{"example.com:8080", "example.com:8080"},
+	}
+
+	for _, test := range hostHeaders {
+		if header := (Request{Host: test.host}).GetHostHeader(); header != test.header {
+			t.Errorf("Wrong host header: %s, expected %s", header, test.header)
+		}
+	}
+}
+
+func TestIPv6(t *testing.T) {
+	listener, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 is not available: %v", err)
+	}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, req.Host)
+	}))
+	ts.Listener.Close()
+	ts.Listener = listener
+	ts.Start()
+	defer ts.Close()
+
+	r := &Request{
+		Scheme:        "http",
+		Host:          "::1",
+		Port:          listener.Addr().(*net.TCPAddr).Port,
+		URI:           "/",
+		Timeout:       30,
+		AddressFamily: "tcp6",
+	}
+	e := &Expected{
+		StatusCodes: []int{200},
+		BodyTexts:   []string{"[::1]"},
+	}
+
+	msg, code, err := Check(r, e)
+
+	if !strings.HasPrefix(msg, "OK") {
+		t.Errorf("Wrong message: %s", msg)
+	}
+
+	if code != EXIT_OK {
+		t.Errorf("Wrong exit code: %d", code)
+	}
+
+	if err != nil {
+		t.Errorf("Returned error is not nil")
+	}
+}
+
+func TestAddressFamily(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, "OK")
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		addressFamily string
+		code          int
+		msg           string
+	}{
+		{"", EXIT_OK, "OK"},
+		{"tcp4", EXIT_OK, "OK"},
+		{"tcp6", EXIT_CRITICAL, "CRITICAL - "},
+	}
+
+	for _, test := range tests {
+		r := newTestRequest(ts, "/")
+		r.AddressFamily = test.addressFamily
+		e := &Expected{
+			StatusCodes: []int{200},
+		}
+
+		msg, code, err := Check(r, e)
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message [%s]: %s", test.addressFamily, msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code [%s]: %d", test.addressFamily, code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil [%s]", test.addressFamily)
+		}
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "app.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("Unix sockets are not available: %v", err)
+	}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "%s %s", req.Host, req.URL.Path)
+	}))
+	ts.Listener.Close()
+	ts.Listener = listener
+	ts.StartTLS()
+	defer ts.Close()
+
+	r := &Request{
+		Scheme:      "https",
+		Host:        "app.example.test",
+		Port:        443,
+		URI:         "/status",
+		Timeout:     30,
+		SSLNoVerify: true,
+		UnixSocket:  socket,
+	}
+	e := &Expected{
+		StatusCodes: []int{200},
+		BodyTexts:   []string{"app.example.test /status"},
+	}
+
+	msg, code, err := Check(r, e)
+
+	if !strings.HasPrefix(msg, "OK") {
+		t.Errorf("Wrong message: %s", msg)
+	}
+
+	if code != EXIT_OK {
+		t.Errorf("Wrong exit code: %d", code)
+	}
+
+	if err != nil {
+		t.Errorf("Returned error is not nil")
+	}
+}
+
+func TestProtocols(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, req.Proto)
+	})
+
+	tlsServer := httptest.NewUnstartedServer(handler)
+	tlsServer.EnableHTTP2 = true
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	h2cServer := httptest.NewUnstartedServer(handler)
+	h2cServer.Config.Protocols = new(http.Protocols)
+	h2cServer.Config.Protocols.SetHTTP1(true)
+	h2cServer.Config.Protocols.SetUnencryptedHTTP2(true)
+	h2cServer.Start()
+	defer h2cServer.Close()
+
+	tests := []struct {
+		ts       *httptest.Server
+		protocol string
+		expected string
+		code     int
+		msg      string
+	}{
+		{tlsServer, "", PROTO_H2, EXIT_OK, "OK - Got response HTTP/2.0 200"},
+		{tlsServer, PROTO_HTTP1, "", EXIT_OK, "OK - Got response HTTP/1.1 200"},
+		{tlsServer, PROTO_HTTP1, PROTO_H2, EXIT_CRITICAL, "CRITICAL - Got protocol http/1.1, expected h2"},
+		{tlsServer, PROTO_H2, PROTO_H2, EXIT_OK, "OK - Got response HTTP/2.0 200"},
+		{h2cServer, "", PROTO_H2C, EXIT_CRITICAL, "CRITICAL - Got protocol http/1.1, expected h2c"},
+		{h2cServer, PROTO_H2C, PROTO_H2C, EXIT_OK, "OK - Got response HTTP/2.0 200"},
+	}
+
+	for _, test := range tests {
+		r := newTestRequest(test.ts, "/")
+		r.SSLNoVerify = true
+		r.Protocol = test.protocol
+		e := &Expected{
+			StatusCodes: []int{200},
+			Protocol:    test.expected,
+		}
+
+		msg, code, err := Check(r, e)
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message [%s %s]: %s", test.protocol, test.expected, msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code [%s %s]: %d", test.protocol, test.expected, code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil [%s %s]", test.protocol, test.expected)
+		}
+	}
+}
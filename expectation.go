@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Expectation operators
+	EXPECT_EQUAL         = "=="
+	EXPECT_NOT_EQUAL     = "!="
+	EXPECT_LESS          = "<"
+	EXPECT_LESS_EQUAL    = "<="
+	EXPECT_GREATER       = ">"
+	EXPECT_GREATER_EQUAL = ">="
+	EXPECT_MATCH         = "~"
+	EXPECT_EXISTS        = "exists"
+	EXPECT_NOT_EXISTS    = "!exists"
+)
+
+// Operators ordered so that longer prefixes are tried first
+var expectOperators = []string{
+	EXPECT_EQUAL,
+	EXPECT_NOT_EQUAL,
+	EXPECT_LESS_EQUAL,
+	EXPECT_GREATER_EQUAL,
+	EXPECT_LESS,
+	EXPECT_GREATER,
+	EXPECT_MATCH,
+}
+
+// Expected value of JSON path, XPath or CSS selector
+type Expectation struct {
+	Operator string
+	Value    string
+	Pattern  *regexp.Regexp
+}
+
+// Parses expectation ex. UP, !=DOWN, >=5, ~^2\.\d+, exists, !exists
+func ParseExpectation(expectation string) (Expectation, error) {
+	parsed := Expectation{Operator: EXPECT_EQUAL, Value: expectation}
+	if expectation == EXPECT_EXISTS || expectation == EXPECT_NOT_EXISTS {
+		parsed.Operator = expectation
+		parsed.Value = ""
+		return parsed, nil
+	}
+
+	for _, operator := range expectOperators {
+		if strings.HasPrefix(expectation, operator) {
+			parsed.Operator = operator
+			parsed.Value = strings.TrimSpace(expectation[len(operator):])
+			break
+		}
+	}
+
+	var err error
+	switch parsed.Operator {
+	case EXPECT_LESS, EXPECT_LESS_EQUAL, EXPECT_GREATER, EXPECT_GREATER_EQUAL:
+		if _, err := strconv.ParseFloat(parsed.Value, 64); err != nil {
+			return parsed, fmt.Errorf("expectation '%s' requires a number", expectation)
+		}
+	case EXPECT_MATCH:
+		parsed.Pattern, err = regexp.Compile(parsed.Value)
+		if err != nil {
+			return parsed, err
+		}
+	}
+
+	return parsed, nil
+}
+
+// Compares values as numbers if both are numbers ex. 5 and 5.0, as strings otherwise
+func equalValues(value string, expected string) bool {
+	number, err := parseNumber(value)
+	if err != nil {
+		return value == expected
+	}
+	expectedNumber, err := parseNumber(expected)
+	if err != nil {
+		return value == expected
+	}
+	return number == expectedNumber
+}
+
+// Evaluates expectation against found value, returns failure description
+func (e Expectation) Evaluate(subject string, value string, found bool) (string, bool) {
+	switch e.Operator {
+	case EXPECT_EXISTS:
+		if !found {
+			return fmt.Sprintf("%s not found", subject), false
+		}
+		return "", true
+	case EXPECT_NOT_EXISTS:
+		if found {
+			return fmt.Sprintf("%s found with value '%s', expected it to be absent", subject, value), false
+		}
+		return "", true
+	}
+
+	if !found {
+		return fmt.Sprintf("%s not found", subject), false
+	}
+
+	switch e.Operator {
+	case EXPECT_EQUAL:
+		if !equalValues(value, e.Value) {
+			return fmt.Sprintf("%s is '%s', expected '%s'", subject, value, e.Value), false
+		}
+	case EXPECT_NOT_EQUAL:
+		if equalValues(value, e.Value) {
+			return fmt.Sprintf("%s is '%s', expected anything else", subject, value), false
+		}
+	case EXPECT_MATCH:
+		if !e.Pattern.MatchString(value) {
+			return fmt.Sprintf("%s is '%s', expected to match '%s'", subject, value, e.Value), false
+		}
+	default:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Sprintf("%s is '%s', expected a number", subject, value), false
+		}
+		expected, _ := strconv.ParseFloat(e.Value, 64)
+		var ok bool
+		switch e.Operator {
+		case EXPECT_LESS:
+			ok = number < expected
+		case EXPECT_LESS_EQUAL:
+			ok = number <= expected
+		case EXPECT_GREATER:
+			ok = number > expected
+		case EXPECT_GREATER_EQUAL:
+			ok = number >= expected
+		}
+		if !ok {
+			return fmt.Sprintf("%s is '%s', expected %s %s", subject, value, e.Operator, e.Value), false
+		}
+	}
+
+	return "", true
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"sort"
+	"strings"
+)
+
+const (
+	// Health response formats
+	HEALTH_AUTO   = "auto"
+	HEALTH_SPRING = "spring"
+	HEALTH_IETF   = "health+json"
+)
+
+// Health check component, nested components are joined by '/'
+type HealthComponent struct {
+	Name   string
+	Status string
+	Output string
+}
+
+// Health response
+type Health struct {
+	Status     string
+	Components []HealthComponent
+}
+
+// Maps health status to exit code, accepts both Spring Boot and IETF statuses
+func healthExitCode(status string) int {
+	switch strings.ToLower(status) {
+	case "up", "pass", "ok":
+		return EXIT_OK
+	case "warn", "warning", "degraded":
+		return EXIT_WARNING
+	case "down", "fail", "error", "out_of_service", "critical":
+		return EXIT_CRITICAL
+	}
+	return EXIT_UNKNOWN
+}
+
+// Detects health response format
+func detectHealthFormat(document map[string]interface{}, contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/health+json" {
+		return HEALTH_IETF
+	}
+	if _, ok := document["checks"]; ok {
+		return HEALTH_IETF
+	}
+	status, _ := document["status"].(string)
+	switch strings.ToLower(status) {
+	case "pass", "warn", "fail":
+		return HEALTH_IETF
+	}
+	return HEALTH_SPRING
+}
+
+// Collects Spring Boot Actuator components, Spring Boot 2.0 and 2.1 use `details` instead of `components`, components without status are skipped
+func springComponents(prefix string, node map[string]interface{}) []HealthComponent {
+	children, ok := node["components"].(map[string]interface{})
+	if !ok {
+		children, _ = node["details"].(map[string]interface{})
+	}
+
+	names := make([]string, 0, len(children))
+	for name := range children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var components []HealthComponent
+	for _, name := range names {
+		child, ok := children[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		status, ok := child["status"].(string)
+		if !ok {
+			continue
+		}
+		component := HealthComponent{Name: prefix + name, Status: status}
+		if details, ok := child["details"].(map[string]interface{}); ok {
+			if output, ok := details["error"].(string); ok {
+				component.Output = output
+			}
+		}
+		components = append(components, component)
+		components = append(components, springComponents(prefix+name+"/", child)...)
+	}
+	return components
+}
+
+// Collects IETF health check components from `checks`, checks without status are skipped
+func ietfComponents(document map[string]interface{}) []HealthComponent {
+	checks, _ := document["checks"].(map[string]interface{})
+
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var components []HealthComponent
+	for _, name := range names {
+		results, ok := checks[name].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, result := range results {
+			check, ok := result.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			component := HealthComponent{Name: name}
+			if componentID, ok := check["componentId"].(string); ok && len(results) > 1 {
+				component.Name = fmt.Sprintf("%s/%s", name, componentID)
+			}
+			status, ok := check["status"].(string)
+			if !ok || len(status) == 0 {
+				continue
+			}
+			component.Status = status
+			component.Output, _ = check["output"].(string)
+			components = append(components, component)
+		}
+	}
+	return components
+}
+
+// Parses health response
+func ParseHealth(document interface{}, format string, contentType string) (Health, error) {
+	var health Health
+
+	object, ok := document.(map[string]interface{})
+	if !ok {
+		return health, fmt.Errorf("expected JSON object")
+	}
+	health.Status, ok = object["status"].(string)
+	if !ok {
+		return health, fmt.Errorf("missing status")
+	}
+
+	if format == HEALTH_AUTO {
+		format = detectHealthFormat(object, contentType)
+	}
+	if format == HEALTH_IETF {
+		health.Components = ietfComponents(object)
+	} else {
+		health.Components = springComponents("", object)
+	}
+
+	return health, nil
+}
+
+// Parses response body as health response
+func parseHealthBody(body []byte, format string, contentType string) (Health, error) {
+	document, err := decodeJSON(body)
+	if err != nil {
+		return Health{}, err
+	}
+	return ParseHealth(document, format, contentType)
+}
+
+// Health check helper, returns long output with failing components
+func checkHealth(health Health) (string, int, []string) {
+	exitCode := healthExitCode(health.Status)
+	var failing []string
+	var longOutput []string
+	for _, component := range health.Components {
+		componentExit := healthExitCode(component.Status)
+		if componentExit == EXIT_OK {
+			continue
+		}
+		exitCode = worseExitCode(exitCode, componentExit)
+		failing = append(failing, component.Name)
+		line := fmt.Sprintf("%s: %s", component.Name, component.Status)
+		if len(component.Output) > 0 {
+			line = fmt.Sprintf("%s - %s", line, component.Output)
+		}
+		longOutput = append(longOutput, line)
+	}
+
+	if exitCode == EXIT_OK {
+		return "", EXIT_OK, nil
+	}
+
+	msg := fmt.Sprintf("%s - Health status %s", exitLookup[exitCode], health.Status)
+	if len(failing) > 0 {
+		msg = fmt.Sprintf("%s, failing components: %s", msg, strings.Join(failing, ", "))
+	}
+	return msg, exitCode, longOutput
+}
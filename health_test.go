@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHealthFormats(t *testing.T) {
+	tests := []struct {
+		format      string
+		contentType string
+		statusCode  int
+		body        string
+		code        int
+		msg         string
+		longOutput  string
+	}{
+		{HEALTH_AUTO, "application/json", 200, `{"status": "UP", "components": {"db": {"status": "UP"}}}`, EXIT_OK, "OK", ""},
+		{HEALTH_SPRING, "application/json", 503, `{"status": "DOWN", "components": {"db": {"status": "DOWN", "details": {"error": "Connection refused"}}, "diskSpace": {"status": "UP"}}}`, EXIT_CRITICAL, "CRITICAL - Health status DOWN, failing components: db|", "db: DOWN - Connection refused"},
+		{HEALTH_AUTO, "application/json", 200, `{"status": "UP", "details": {"redis": {"status": "UNKNOWN"}, "mail": {"status": "UP"}}}`, EXIT_UNKNOWN, "UNKNOWN - Health status UP, failing components: redis|", "redis: UNKNOWN"},
+		{HEALTH_AUTO, "application/json", 200, `{"status": "UP", "components": {"db": {"status": "UP", "components": {"primary": {"status": "UP"}, "replica": {"status": "DEGRADED"}}}}}`, EXIT_WARNING, "WARNING - Health status UP, failing components: db/replica|", "db/replica: DEGRADED"},
+		{HEALTH_AUTO, "application/health+json", 200, `{"status": "warn", "checks": {"cpu:utilization": [{"componentId": "node-1", "status": "pass"}, {"componentId": "node-2", "status": "warn", "output": "90% used"}]}}`, EXIT_WARNING, "WARNING - Health status warn, failing components: cpu:utilization/node-2|", "cpu:utilization/node-2: warn - 90% used"},
+		{HEALTH_IETF, "application/json", 503, `{"status": "fail", "checks": {"database:responseTime": [{"status": "fail", "output": "timeout"}]}}`, EXIT_CRITICAL, "CRITICAL - Health status fail, failing components: database:responseTime|", "database:responseTime: fail - timeout"},
+		{HEALTH_AUTO, "application/json", 200, `{"status": "pass"}`, EXIT_OK, "OK", ""},
+		{HEALTH_IETF, "application/json", 200, `{"status": "pass", "checks": {"uptime": [{"observedValue": 1209600}], "memory": [{"status": "pass"}]}}`, EXIT_OK, "OK", ""},
+		{HEALTH_SPRING, "application/json", 200, `{"status": "UP", "components": {"ping": {}, "db": {"status": "UP"}}}`, EXIT_OK, "OK", ""},
+		{HEALTH_AUTO, "text/html", 200, `<html>OK</html>`, EXIT_CRITICAL, "CRITICAL - Response body is not a health response", ""},
+		{HEALTH_AUTO, "text/html", 503, `<html>Service Unavailable</html>`, EXIT_CRITICAL, "CRITICAL - Got  response HTTP/1.1 503, expected 200", ""},
+	}
+
+	for _, test := range tests {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", test.contentType)
+			w.WriteHeader(test.statusCode)
+			fmt.Fprint(w, test.body)
+		}))
+
+		r := newTestRequest(ts, "/actuator/health")
+		e := &Expected{
+			StatusCodes:  []int{200},
+			HealthFormat: test.format,
+		}
+
+		msg, code, err := Check(r, e)
+		ts.Close()
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message [body: %s]: %s", test.body, msg)
+		}
+
+		if len(test.longOutput) > 0 && !strings.HasSuffix(msg, "\n"+test.longOutput) {
+			t.Errorf("Wrong long output [body: %s]: %s", test.body, msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code [body: %s]: %d", test.body, code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil [body: %s]", test.body)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// HTTP/3 transport factory, dialer respects resolve entries, DNS server and address family
+func newHTTP3Transport(r *Request, TLSConfig *tls.Config) *http3.Transport {
+	quicConfig := &quic.Config{}
+	if r.TLSTimeout > 0 {
+		quicConfig.HandshakeIdleTimeout = secondsDuration(r.TLSTimeout)
+	}
+
+	return &http3.Transport{
+		TLSClientConfig: TLSConfig,
+		QUICConfig:      quicConfig,
+		Dial: func(ctx context.Context, address string, tlsConfig *tls.Config, quicConfig *quic.Config) (*quic.Conn, error) {
+			host, port, err := net.SplitHostPort(resolveAddress(r, address))
+			if err != nil {
+				return nil, err
+			}
+			udpAddr, err := lookupUDPAddr(ctx, r, host, port)
+			if err != nil {
+				return nil, err
+			}
+
+			// QUIC handshake is reported as TLS handshake
+			trace := httptrace.ContextClientTrace(ctx)
+			if trace != nil && trace.TLSHandshakeStart != nil {
+				trace.TLSHandshakeStart()
+			}
+			conn, err := quic.DialAddrEarly(ctx, udpAddr.String(), tlsConfig, quicConfig)
+			if trace != nil && trace.TLSHandshakeDone != nil {
+				var state tls.ConnectionState
+				if conn != nil {
+					state = conn.ConnectionState().TLS
+				}
+				trace.TLSHandshakeDone(state, err)
+			}
+			return conn, err
+		},
+	}
+}
+
+// Looks up UDP address of the host, address family is respected
+func lookupUDPAddr(ctx context.Context, r *Request, host string, port string) (*net.UDPAddr, error) {
+	portNumber, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return &net.UDPAddr{IP: ip, Port: portNumber}, nil
+	}
+
+	ips, err := newResolver(r).LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		if r.AddressFamily == "tcp4" && ip.IP.To4() == nil {
+			continue
+		}
+		if r.AddressFamily == "tcp6" && ip.IP.To4() != nil {
+			continue
+		}
+		return &net.UDPAddr{IP: ip.IP, Port: portNumber, Zone: ip.Zone}, nil
+	}
+	return nil, fmt.Errorf("no suitable address found for %s", host)
+}
+
+// Alt-Svc check helper, HTTP/3 has to be advertised on the checked port
+func checkAltSvc(values []string, port int) (string, int) {
+	var ports []string
+	for _, value := range values {
+		for _, service := range strings.Split(value, ",") {
+			service = strings.TrimSpace(strings.SplitN(service, ";", 2)[0])
+			serviceParts := strings.SplitN(service, "=", 2)
+			if len(serviceParts) != 2 || serviceParts[0] != "h3" {
+				continue
+			}
+			_, servicePort, err := net.SplitHostPort(strings.Trim(serviceParts[1], "\""))
+			if err != nil {
+				continue
+			}
+			if servicePort == strconv.Itoa(port) {
+				return "", EXIT_OK
+			}
+			ports = append(ports, servicePort)
+		}
+	}
+
+	if len(ports) == 0 {
+		return "WARNING - Alt-Svc does not advertise h3", EXIT_WARNING
+	}
+	return fmt.Sprintf("WARNING - Alt-Svc advertises h3 on port %s, expected %d", strings.Join(ports, ", "), port), EXIT_WARNING
+}
+
+// Requests resource over TCP, returns Alt-Svc check result, perfdata and summary
+func compareTCP(r *Request) (string, int, PerfData, string) {
+	tcpRequest := *r
+	tcpRequest.HTTP3 = false
+	tcpRequest.Protocol = ""
+	perfData := PerfData{Label: "time_tcp", UOM: "s", Min: perfBound(0)}
+
+	client, err := initHTTPClient(&tcpRequest)
+	if err != nil {
+		return fmt.Sprintf("WARNING - TCP request failed: %s", err.Error()), EXIT_WARNING, perfData, "TCP: failed"
+	}
+	defer client.CloseIdleConnections()
+	request, err := newHTTPRequest(&tcpRequest)
+	if err != nil {
+		return fmt.Sprintf("WARNING - TCP request failed: %s", err.Error()), EXIT_WARNING, perfData, "TCP: failed"
+	}
+	setAuthentication(client, request, tcpRequest.Authentication)
+
+	start := time.Now()
+	res, err := client.Do(request)
+	if err != nil {
+		return fmt.Sprintf("WARNING - TCP request failed: %s", err.Error()), EXIT_WARNING, perfData, fmt.Sprintf("TCP: %s", err.Error())
+	}
+	defer res.Body.Close()
+	if _, err := ioutil.ReadAll(res.Body); err != nil {
+		return fmt.Sprintf("WARNING - TCP request failed: %s", err.Error()), EXIT_WARNING, perfData, fmt.Sprintf("TCP: %s", err.Error())
+	}
+	perfData.Value = time.Since(start).Seconds()
+
+	summary := fmt.Sprintf("TCP: Got response %s %d in %ss", res.Proto, res.StatusCode, formatPerfValue(perfData.Value))
+	altSvcMsg, altSvcExit := checkAltSvc(res.Header.Values("Alt-Svc"), r.Port)
+	return altSvcMsg, altSvcExit, perfData, summary
+}
+
+// Checks resource over HTTP/3 and compares it with TCP request advertising HTTP/3 by Alt-Svc
+func CheckHTTP3Comparison(r *Request, e *Expected) (string, int, error) {
+	msg, code, err := Check(r, e)
+	if err != nil {
+		return msg, code, err
+	}
+
+	tcpMsg, tcpExit, tcpPerfData, tcpSummary := compareTCP(r)
+
+	status, perfData, longOutput := splitOutput(msg)
+	if code == EXIT_OK && tcpExit != EXIT_OK {
+		status, code = tcpMsg, tcpExit
+	}
+	if tcpPerfData.Value > 0 {
+		perfData = strings.TrimSpace(perfData + " " + tcpPerfData.String())
+	}
+	return formatOutput(status, perfData, append(longOutput, tcpSummary)), code, nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// Starts TLS server over TCP and HTTP/3 server on the same UDP port, {port} in Alt-Svc is replaced by server port
+func newHTTP3TestServer(t *testing.T, altSvc string) (*httptest.Server, func()) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if len(altSvc) > 0 {
+			_, port, _ := net.SplitHostPort(req.Context().Value(http.LocalAddrContextKey).(net.Addr).String())
+			w.Header().Set("Alt-Svc", strings.ReplaceAll(altSvc, "{port}", port))
+		}
+		fmt.Fprintf(w, "%s %s", req.Proto, req.Host)
+	})
+
+	ts := httptest.NewTLSServer(handler)
+	udpConn, err := net.ListenPacket("udp4", ts.Listener.Addr().String())
+	if err != nil {
+		ts.Close()
+		t.Skipf("UDP port is not available: %v", err)
+	}
+	server := &http3.Server{
+		Handler:   handler,
+		TLSConfig: http3.ConfigureTLSConfig(ts.TLS.Clone()),
+	}
+	go server.Serve(udpConn)
+
+	return ts, func() {
+		server.Close()
+		udpConn.Close()
+		ts.Close()
+	}
+}
+
+func TestHTTP3(t *testing.T) {
+	ts, closeServer := newHTTP3TestServer(t, "")
+	defer closeServer()
+
+	r := newTestRequest(ts, "/")
+	r.Host = "example.com"
+	r.IPAddress = "127.0.0.1"
+	r.SSLNoVerify = true
+	r.HTTP3 = true
+	e := &Expected{
+		StatusCodes: []int{200},
+		BodyTexts:   []string{"HTTP/3.0 example.com"},
+		Protocol:    PROTO_H3,
+	}
+
+	msg, code, err := Check(r, e)
+
+	if !strings.HasPrefix(msg, "OK - Got response HTTP/3.0 200") {
+		t.Errorf("Wrong message: %s", msg)
+	}
+
+	for _, label := range []string{"time_tls=", "time_ttfb=", "time_transfer="} {
+		if !strings.Contains(msg, label) {
+			t.Errorf("Missing %s perfdata: %s", label, msg)
+		}
+	}
+
+	if code != EXIT_OK {
+		t.Errorf("Wrong exit code: %d", code)
+	}
+
+	if err != nil {
+		t.Errorf("Returned error is not nil")
+	}
+}
+
+func TestHTTP3Comparison(t *testing.T) {
+	tests := []struct {
+		altSvc string
+		code   int
+		msg    string
+	}{
+		{`h3=":{port}"; ma=86400`, EXIT_OK, "OK - Got response HTTP/3.0 200"},
+		{"", EXIT_WARNING, "WARNING - Alt-Svc does not advertise h3"},
+		{`h3=":1"`, EXIT_WARNING, "WARNING - Alt-Svc advertises h3 on port 1, expected "},
+	}
+
+	for _, test := range tests {
+		ts, closeServer := newHTTP3TestServer(t, test.altSvc)
+
+		r := newTestRequest(ts, "/")
+		r.SSLNoVerify = true
+		r.HTTP3 = true
+		e := &Expected{
+			StatusCodes: []int{200},
+		}
+
+		msg, code, err := CheckHTTP3Comparison(r, e)
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message [%s]: %s", test.altSvc, msg)
+		}
+
+		if !strings.Contains(msg, "time_tcp=") || !strings.Contains(msg, "\nTCP: Got response HTTP/1.1 200") {
+			t.Errorf("Missing TCP comparison [%s]: %s", test.altSvc, msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code [%s]: %d", test.altSvc, code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil [%s]", test.altSvc)
+		}
+
+		closeServer()
+	}
+}
+
+func TestCompareTCPAuthentication(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		message, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(req.Header.Get("Authorization"), "NTLM "))
+		switch {
+		case len(message) >= 12 && binary.LittleEndian.Uint32(message[8:]) == 1:
+			w.Header().Set("WWW-Authenticate", "NTLM "+base64.StdEncoding.EncodeToString(ntlmChallenge()))
+			w.WriteHeader(http.StatusUnauthorized)
+		case len(message) >= 12 && binary.LittleEndian.Uint32(message[8:]) == 3:
+			_, port, _ := net.SplitHostPort(req.Context().Value(http.LocalAddrContextKey).(net.Addr).String())
+			w.Header().Set("Alt-Svc", fmt.Sprintf(`h3=":%s"`, port))
+			fmt.Fprint(w, "authenticated")
+		default:
+			w.Header().Set("WWW-Authenticate", "NTLM")
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer ts.Close()
+
+	r := newTestRequest(ts, "/")
+	r.SSLNoVerify = true
+	r.HTTP3 = true
+	r.Authentication = Authentication{Type: AUTH_NTLM, User: "DOMAIN\\user", Password: "secret"}
+
+	msg, code, _, summary := compareTCP(r)
+
+	if msg != "" {
+		t.Errorf("Wrong message: %s", msg)
+	}
+
+	if !strings.HasPrefix(summary, "TCP: Got response HTTP/1.1 200 in ") {
+		t.Errorf("Wrong summary: %s", summary)
+	}
+
+	if code != EXIT_OK {
+		t.Errorf("Wrong exit code: %d", code)
+	}
+}
+
+func TestCheckAltSvc(t *testing.T) {
+	tests := []struct {
+		values []string
+		code   int
+		msg    string
+	}{
+		{[]string{`h3=":443"; ma=86400`}, EXIT_OK, ""},
+		{[]string{`h3-29=":443", h3="cdn.example.com:443"; ma=3600`}, EXIT_OK, ""},
+		{[]string{`h2=":443"`, `h3=":443"`}, EXIT_OK, ""},
+		{nil, EXIT_WARNING, "WARNING - Alt-Svc does not advertise h3"},
+		{[]string{"clear"}, EXIT_WARNING, "WARNING - Alt-Svc does not advertise h3"},
+		{[]string{`h3=":8443"`}, EXIT_WARNING, "WARNING - Alt-Svc advertises h3 on port 8443, expected 443"},
+	}
+
+	for _, test := range tests {
+		msg, code := checkAltSvc(test.values, 443)
+
+		if msg != test.msg {
+			t.Errorf("Wrong message %v: %s", test.values, msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code %v: %d", test.values, code)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parsed JSONPath, segments are either object keys (string) or array indexes (int)
+type JSONPath struct {
+	Raw      string
+	Segments []interface{}
+}
+
+// JSON assertion
+type JSONCheck struct {
+	Path        JSONPath
+	Expectation Expectation
+}
+
+// Numeric JSON value compared against thresholds and exported as perfdata
+type JSONMetric struct {
+	Label     string
+	Path      JSONPath
+	Threshold Threshold
+}
+
+// Parses subset of JSONPath: $.key, $['key'], $[0], $.list[-1].key
+func ParseJSONPath(path string) (JSONPath, error) {
+	jsonPath := JSONPath{Raw: path}
+	if !strings.HasPrefix(path, "$") {
+		return jsonPath, fmt.Errorf("JSON path '%s' must start with '$'", path)
+	}
+
+	rest := path[1:]
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if len(key) == 0 {
+				return jsonPath, fmt.Errorf("JSON path '%s' contains empty key", path)
+			}
+			jsonPath.Segments = append(jsonPath.Segments, key)
+			rest = rest[end+1:]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return jsonPath, fmt.Errorf("JSON path '%s' contains unclosed bracket", path)
+			}
+			selector := rest[1:end]
+			if len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0] {
+				jsonPath.Segments = append(jsonPath.Segments, selector[1:len(selector)-1])
+			} else {
+				index, err := strconv.Atoi(selector)
+				if err != nil {
+					return jsonPath, fmt.Errorf("JSON path '%s' contains invalid index '%s'", path, selector)
+				}
+				jsonPath.Segments = append(jsonPath.Segments, index)
+			}
+			rest = rest[end+1:]
+		default:
+			return jsonPath, fmt.Errorf("JSON path '%s' is invalid near '%s'", path, rest)
+		}
+	}
+
+	return jsonPath, nil
+}
+
+// Looks up value in decoded JSON document
+func (p JSONPath) Lookup(document interface{}) (interface{}, bool) {
+	current := document
+	for _, segment := range p.Segments {
+		switch segment := segment.(type) {
+		case string:
+			object, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			current, ok = object[segment]
+			if !ok {
+				return nil, false
+			}
+		case int:
+			array, ok := current.([]interface{})
+			if !ok {
+				return nil, false
+			}
+			index := segment
+			if index < 0 {
+				index += len(array)
+			}
+			if index < 0 || index >= len(array) {
+				return nil, false
+			}
+			current = array[index]
+		}
+	}
+	return current, true
+}
+
+// Parses JSON assertion, see ParseExpectation
+func ParseJSONCheck(path string, expectation string) (JSONCheck, error) {
+	jsonPath, err := ParseJSONPath(path)
+	if err != nil {
+		return JSONCheck{}, err
+	}
+
+	parsedExpectation, err := ParseExpectation(expectation)
+	if err != nil {
+		return JSONCheck{}, err
+	}
+
+	return JSONCheck{Path: jsonPath, Expectation: parsedExpectation}, nil
+}
+
+// Parses metric ex. $.queue.depth, depth=$.queue.depth;10;20, $.pool.idle;5:;2:
+func ParseJSONMetric(metric string) (JSONMetric, error) {
+	var jsonMetric JSONMetric
+
+	parts := strings.Split(metric, ";")
+	if len(parts) > 3 {
+		return jsonMetric, fmt.Errorf("JSON metric '%s' has too many fields", metric)
+	}
+
+	path := parts[0]
+	if index := strings.Index(path, "="); index > 0 && !strings.Contains(path[:index], "$") {
+		jsonMetric.Label = path[:index]
+		path = path[index+1:]
+	}
+
+	jsonPath, err := ParseJSONPath(path)
+	if err != nil {
+		return jsonMetric, err
+	}
+	jsonMetric.Path = jsonPath
+
+	if len(jsonMetric.Label) == 0 {
+		jsonMetric.Label = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	}
+	if len(jsonMetric.Label) == 0 {
+		jsonMetric.Label = "value"
+	}
+
+	var warning, critical string
+	if len(parts) > 1 {
+		warning = parts[1]
+	}
+	if len(parts) > 2 {
+		critical = parts[2]
+	}
+	jsonMetric.Threshold, err = ParseThreshold(warning, critical)
+	if err != nil {
+		return jsonMetric, fmt.Errorf("JSON metric '%s' has %s", metric, err.Error())
+	}
+
+	return jsonMetric, nil
+}
+
+// Decodes JSON body keeping numbers in their original form
+func decodeJSON(body []byte) (interface{}, error) {
+	var document interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after top-level value")
+	}
+	return document, nil
+}
+
+// Formats JSON value for comparison and messages
+func formatJSONValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	case bool:
+		return strconv.FormatBool(value)
+	default:
+		encoded, _ := json.Marshal(value)
+		return string(encoded)
+	}
+}
+
+// Evaluates single JSON assertion, returns failure description
+func (c JSONCheck) Evaluate(document interface{}) (string, bool) {
+	value, found := c.Path.Lookup(document)
+	return c.Expectation.Evaluate(fmt.Sprintf("JSON path '%s'", c.Path.Raw), formatJSONValue(value), found)
+}
+
+// JSON check helper
+func checkJSON(document interface{}, e *Expected) (string, int) {
+	for _, jsonCheck := range e.JSONChecks {
+		if failure, ok := jsonCheck.Evaluate(document); !ok {
+			return fmt.Sprintf("CRITICAL - %s", failure), EXIT_CRITICAL
+		}
+	}
+
+	return "", EXIT_OK
+}
+
+// JSON metrics check helper, returns perfdata of all found metrics
+func checkJSONMetrics(document interface{}, e *Expected) (string, int, PerfDataList) {
+	var perfData PerfDataList
+	var failures []string
+	exitCode := EXIT_OK
+
+	for _, jsonMetric := range e.JSONMetrics {
+		value, found := jsonMetric.Path.Lookup(document)
+		if !found {
+			failures = append(failures, fmt.Sprintf("JSON path '%s' not found", jsonMetric.Path.Raw))
+			exitCode = EXIT_CRITICAL
+			continue
+		}
+
+		// Only JSON numbers are accepted, numeric strings are not converted
+		jsonNumber, ok := value.(json.Number)
+		if !ok {
+			failures = append(failures, fmt.Sprintf("JSON path '%s' is '%s', expected a number", jsonMetric.Path.Raw, formatJSONValue(value)))
+			exitCode = EXIT_CRITICAL
+			continue
+		}
+		number, err := jsonNumber.Float64()
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("JSON path '%s' is '%s', expected a number", jsonMetric.Path.Raw, formatJSONValue(value)))
+			exitCode = EXIT_CRITICAL
+			continue
+		}
+
+		formatted := strconv.FormatFloat(number, 'f', -1, 64)
+		perfData = append(perfData, PerfData{Label: jsonMetric.Label, Value: number, Threshold: jsonMetric.Threshold})
+
+		metricExit := jsonMetric.Threshold.Evaluate(number)
+		if metricExit == EXIT_OK {
+			continue
+		}
+		if metricExit == EXIT_CRITICAL {
+			failures = append(failures, fmt.Sprintf("JSON path '%s' is %s, critical threshold %s", jsonMetric.Path.Raw, formatted, jsonMetric.Threshold.Critical.Raw))
+		} else {
+			failures = append(failures, fmt.Sprintf("JSON path '%s' is %s, warning threshold %s", jsonMetric.Path.Raw, formatted, jsonMetric.Threshold.Warning.Raw))
+		}
+		exitCode = worseExitCode(exitCode, metricExit)
+	}
+
+	switch exitCode {
+	case EXIT_CRITICAL:
+		return fmt.Sprintf("CRITICAL - %s", strings.Join(failures, ", ")), exitCode, perfData
+	case EXIT_WARNING:
+		return fmt.Sprintf("WARNING - %s", strings.Join(failures, ", ")), exitCode, perfData
+	}
+	return "", EXIT_OK, perfData
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		segments []interface{}
+	}{
+		{"$", nil},
+		{"$.status", []interface{}{"status"}},
+		{"$.components.db.status", []interface{}{"components", "db", "status"}},
+		{"$['weird.key'][0]", []interface{}{"weird.key", 0}},
+		{"$.items[-1].name", []interface{}{"items", -1, "name"}},
+	}
+
+	for _, test := range tests {
+		jsonPath, err := ParseJSONPath(test.path)
+		if err != nil {
+			t.Errorf("Unexpected error [path: %s]: %v", test.path, err)
+			continue
+		}
+		if fmt.Sprint(jsonPath.Segments) != fmt.Sprint(test.segments) {
+			t.Errorf("Wrong segments [path: %s]: %v", test.path, jsonPath.Segments)
+		}
+	}
+
+	for _, path := range []string{"status", "$.", "$[0", "$[x]", "$..a"} {
+		if _, err := ParseJSONPath(path); err == nil {
+			t.Errorf("Invalid path accepted [path: %s]", path)
+		}
+	}
+}
+
+func TestJSONChecks(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"status": "UP", "version": "2.4.1", "queue": {"depth": 12, "ratio": 5.0, "limit": 5e0}, "nodes": [{"up": true}, {"up": false}], "error": null}`)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		path        string
+		expectation string
+		code        int
+		msg         string
+	}{
+		{"$.status", "UP", EXIT_OK, "OK"},
+		{"$.status", "==UP", EXIT_OK, "OK"},
+		{"$.status", "DOWN", EXIT_CRITICAL, "CRITICAL - JSON path '$.status' is 'UP', expected 'DOWN'"},
+		{"$.status", "!=DOWN", EXIT_OK, "OK"},
+		{"$.version", "~^2\\.", EXIT_OK, "OK"},
+		{"$.queue.depth", "<=12", EXIT_OK, "OK"},
+		{"$.queue.depth", ">100", EXIT_CRITICAL, "CRITICAL - JSON path '$.queue.depth' is '12', expected > 100"},
+		{"$.queue.ratio", "5", EXIT_OK, "OK"},
+		{"$.queue.limit", "==5", EXIT_OK, "OK"},
+		{"$.queue.ratio", "!=5", EXIT_CRITICAL, "CRITICAL - JSON path '$.queue.ratio' is '5.0', expected anything else"},
+		{"$.queue.limit", "6", EXIT_CRITICAL, "CRITICAL - JSON path '$.queue.limit' is '5e0', expected '6'"},
+		{"$.version", "2.4", EXIT_CRITICAL, "CRITICAL - JSON path '$.version' is '2.4.1', expected '2.4'"},
+		{"$.status", ">1", EXIT_CRITICAL, "CRITICAL - JSON path '$.status' is 'UP', expected a number"},
+		{"$.nodes[1].up", "false", EXIT_OK, "OK"},
+		{"$.nodes[-1].up", "true", EXIT_CRITICAL, "CRITICAL"},
+		{"$.error", "null", EXIT_OK, "OK"},
+		{"$.queue", EXPECT_EXISTS, EXIT_OK, "OK"},
+		{"$.database", EXPECT_EXISTS, EXIT_CRITICAL, "CRITICAL - JSON path '$.database' not found"},
+		{"$.database", EXPECT_NOT_EXISTS, EXIT_OK, "OK"},
+		{"$.queue.depth", EXPECT_NOT_EXISTS, EXIT_CRITICAL, "CRITICAL - JSON path '$.queue.depth' found"},
+	}
+
+	for _, test := range tests {
+		jsonCheck, err := ParseJSONCheck(test.path, test.expectation)
+		if err != nil {
+			t.Errorf("Unexpected error [path: %s]: %v", test.path, err)
+			continue
+		}
+
+		r := newTestRequest(ts, "/health")
+		e := &Expected{
+			StatusCodes: []int{200},
+			JSONChecks:  []JSONCheck{jsonCheck},
+		}
+
+		msg, code, err := Check(r, e)
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message [path: %s, expectation: %s]: %s", test.path, test.expectation, msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code [path: %s, expectation: %s]: %d", test.path, test.expectation, code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil [path: %s]", test.path)
+		}
+	}
+}
+
+func TestJSONInvalidBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, "<html>Service Unavailable</html>")
+	}))
+	defer ts.Close()
+
+	jsonCheck, _ := ParseJSONCheck("$.status", "UP")
+	r := newTestRequest(ts, "/health")
+	e := &Expected{
+		StatusCodes: []int{200},
+		JSONChecks:  []JSONCheck{jsonCheck},
+	}
+
+	msg, code, _ := Check(r, e)
+
+	if !strings.HasPrefix(msg, "CRITICAL - Response body is not valid JSON") {
+		t.Errorf("Wrong message: %s", msg)
+	}
+
+	if code != EXIT_CRITICAL {
+		t.Errorf("Wrong exit code: %d", code)
+	}
+}
+
+func TestJSONMetrics(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"queue": {"depth": 120}, "db": {"pool": {"active": 4, "idle": 1}}, "name": "worker", "lag": "42"}`)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		metrics  []string
+		code     int
+		msg      string
+		perfData string
+	}{
+		{[]string{"$.queue.depth"}, EXIT_OK, "OK", " queue.depth=120"},
+		{[]string{"queue=$.queue.depth;100;500"}, EXIT_WARNING, "WARNING - JSON path '$.queue.depth' is 120, warning threshold 100", " queue=120;100;500"},
+		{[]string{"$.queue.depth;50;100", "$.db.pool.active;10;20"}, EXIT_CRITICAL, "CRITICAL - JSON path '$.queue.depth' is 120, critical threshold 100", " queue.depth=120;50;100 db.pool.active=4;10;20"},
+		{[]string{"idle=$.db.pool.idle;2:;1:"}, EXIT_WARNING, "WARNING", " idle=1;2:;1:"},
+		{[]string{"$.db.pool.waiting"}, EXIT_CRITICAL, "CRITICAL - JSON path '$.db.pool.waiting' not found", ""},
+		{[]string{"$.name"}, EXIT_CRITICAL, "CRITICAL - JSON path '$.name' is 'worker', expected a number", ""},
+		{[]string{"$.lag"}, EXIT_CRITICAL, "CRITICAL - JSON path '$.lag' is '42', expected a number", ""},
+	}
+
+	for _, test := range tests {
+		var metrics []JSONMetric
+		for _, metric := range test.metrics {
+			jsonMetric, err := ParseJSONMetric(metric)
+			if err != nil {
+				t.Fatalf("Unexpected error [metric: %s]: %v", metric, err)
+			}
+			metrics = append(metrics, jsonMetric)
+		}
+
+		r := newTestRequest(ts, "/metrics")
+		e := &Expected{
+			StatusCodes: []int{200},
+			JSONMetrics: metrics,
+		}
+
+		msg, code, err := Check(r, e)
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message %v: %s", test.metrics, msg)
+		}
+
+		if !strings.HasSuffix(msg, "size=98B;;;0"+test.perfData) {
+			t.Errorf("Wrong perfdata %v: %s", test.metrics, msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code %v: %d", test.metrics, code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil %v", test.metrics)
+		}
+	}
+}
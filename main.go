@@ -2,36 +2,93 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/jessevdk/go-flags"
+	"github.com/santhosh-tekuri/jsonschema/v6"
 )
 
 type Options struct {
-	Host                    string `short:"H" description:"Host ex. google.com" default:""`
-	IPAddress               string `short:"I" description:"IPv4 address ex. 8.8.4.4" default:""`
-	URI                     string `short:"u" long:"uri" description:"URI to check" default:"/"`
-	Port                    int    `short:"p" description:"Port ex. 80 for HTTP 443 for HTTPS" default:"80"`
-	SSL                     bool   `short:"S" long:"tls" description:"Use HTTPS"`
-	Timeout                 int    `short:"t" long:"timeout" description:"Timeout" default:"30"`
-	AuthBasic               bool   `long:"auth-basic" description:"Use bacis auth"`
-	AuthNtlm                bool   `long:"auth-ntlm" description:"Use NTLM auth"`
-	Auth                    string `short:"a" long:"auth" description:"ex. user:password" default:""`
-	ExpectedCode            string `short:"e" long:"expect" description:"Expected HTTP code" default:"200"`
-	BodyText                string `short:"s" long:"string" description:"Search for given string in response body" default:""`
-	SSLExpiration           string `short:"C" description:"Check SSL cert expiration" default:""`
-	SSLNoVerify             bool   `short:"k" long:"insecure" description:"Controls whether a client verifies the server's certificate chain and host name"`
-	Verbose                 bool   `short:"v" long:"verbose" description:"Verbose mode"`
-	GuessAuth               bool   `long:"guess-auth" description:"Guess auth type"`
-	FollowRedirects         bool   `long:"follow-redirects" description:"Follow redirects"`
-	WarningTimeout          int    `short:"w" description:"Warning timeout" default:"0"`
-	CriticalTimeout         int    `short:"c" description:"Critical timeout" default:"0"`
-	NoSNI                   bool   `long:"no-sni" description:"Do not use SNI"`
-	ClientCertFile          string `short:"J" long:"client-cert" description:"Name of file containing the client certificate (PEM format) to be used in establishing the SSL session"`
-	PrivateKeyFile          string `short:"K" long:"private-key" description:"Name of file containing the private key (PEM format) matching the client certificate"`
-	DisableTLSRenegotiation bool   `long:"disable-tls-renegotiation" description:"Disable TLS Renegotiation"`
+	Host                    string   `short:"H" description:"Host ex. google.com" default:""`
+	IPAddress               string   `short:"I" description:"IPv4 or IPv6 address ex. 8.8.4.4, 2001:4860:4860::8844" default:""`
+	IPv4                    bool     `short:"4" description:"Connect using IPv4 only"`
+	IPv6                    bool     `short:"6" description:"Connect using IPv6 only"`
+	AllAddresses            bool     `long:"all-addresses" description:"Check every resolved address of the host and aggregate results"`
+	Resolve                 []string `long:"resolve" description:"Connect to address instead of resolving host and port ex. example.com:443:10.0.0.1, can be repeated"`
+	Protocol                string   `long:"protocol" description:"Force protocol http/1.1, h2 (HTTP/2 over TLS) or h2c (HTTP/2 prior knowledge without TLS)" choice:"http/1.1" choice:"h2" choice:"h2c"`
+	ExpectProtocol          string   `long:"expect-proto" description:"Expected negotiated protocol http/1.0, http/1.1, h2, h2c or h3" choice:"http/1.0" choice:"http/1.1" choice:"h2" choice:"h2c" choice:"h3"`
+	HTTP3                   bool     `long:"http3" description:"Use HTTP/3 over QUIC, handshake is reported as TLS phase"`
+	HTTP3Compare            bool     `long:"http3-compare" description:"Compare HTTP/3 with request over TCP, which has to advertise HTTP/3 by Alt-Svc header"`
+	CAFile                  string   `long:"ca-file" description:"PEM file with trusted CA certificates, replaces system roots" default:""`
+	CADir                   string   `long:"ca-dir" description:"Directory with PEM files of trusted CA certificates, replaces system roots" default:""`
+	CASystem                bool     `long:"ca-system" description:"Trust system roots in addition to --ca-file and --ca-dir"`
+	TLSMin                  string   `long:"tls-min" description:"Minimal TLS version" choice:"1.0" choice:"1.1" choice:"1.2" choice:"1.3"`
+	TLSMax                  string   `long:"tls-max" description:"Maximal TLS version" choice:"1.0" choice:"1.1" choice:"1.2" choice:"1.3"`
+	TLSCiphers              string   `long:"tls-ciphers" description:"Comma separated allowed cipher suites up to TLS 1.2 ex. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256" default:""`
+	TLSScan                 bool     `long:"tls-scan" description:"Scan accepted legacy TLS versions and weak cipher suites instead of HTTP check"`
+	TLSScanLegacy           string   `long:"tls-scan-legacy" description:"Severity of accepted TLS 1.0 and 1.1" choice:"ok" choice:"warning" choice:"critical" default:"warning"`
+	TLSScanWeak             string   `long:"tls-scan-weak" description:"Severity of accepted RC4, 3DES and CBC-SHA1 cipher suites" choice:"ok" choice:"warning" choice:"critical" default:"critical"`
+	UnixSocket              string   `long:"unix-socket" description:"Connect to unix socket instead of host ex. /run/app.sock, -H sets Host header (default: localhost)" default:""`
+	DNSServer               string   `long:"dns-server" description:"DNS server to resolve host ex. 8.8.8.8, 10.0.0.53:5353" default:""`
+	URI                     string   `short:"u" long:"uri" description:"URI to check" default:"/"`
+	Port                    int      `short:"p" description:"Port ex. 80 for HTTP 443 for HTTPS" default:"80"`
+	SSL                     bool     `short:"S" long:"tls" description:"Use HTTPS"`
+	Timeout                 string   `short:"t" long:"timeout" description:"Timeout in seconds, ms and s units are accepted ex. 10, 1.5, 500ms" default:"30"`
+	AuthBasic               bool     `long:"auth-basic" description:"Use bacis auth"`
+	AuthNtlm                bool     `long:"auth-ntlm" description:"Use NTLM auth"`
+	Proxy                   string   `long:"proxy" description:"Proxy URL ex. http://proxy:3128, socks5://proxy:1080" default:""`
+	ProxyEnv                bool     `long:"proxy-env" description:"Use proxy from HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables"`
+	ProxyAuth               string   `long:"proxy-auth" description:"Proxy username and password ex. user:password, requires --proxy" default:""`
+	ProxyNtlm               bool     `long:"proxy-ntlm" description:"Use NTLM auth for HTTP proxy, HTTPS requests are tunneled by CONNECT, HTTP requests are forwarded"`
+	Auth                    string   `short:"a" long:"auth" description:"ex. user:password" default:""`
+	ExpectedCode            string   `short:"e" long:"expect" description:"Expected HTTP code" default:"200"`
+	BodyTexts               []string `short:"s" long:"string" description:"Search for given string in response body, can be repeated"`
+	BodyTextsMode           string   `long:"string-mode" description:"Require all given strings or any of them" choice:"all" choice:"any" default:"all"`
+	PageSize                string   `short:"m" long:"pagesize" description:"Minimum page size in bytes, optionally maximum ex. 512, 512:20000 or :20000" default:""`
+	SSLExpiration           string   `short:"C" description:"Check SSL cert expiration" default:""`
+	SSLNoVerify             bool     `short:"k" long:"insecure" description:"Controls whether a client verifies the server's certificate chain and host name"`
+	Verbose                 bool     `short:"v" long:"verbose" description:"Verbose mode"`
+	GuessAuth               bool     `long:"guess-auth" description:"Guess auth type"`
+	FollowRedirects         bool     `long:"follow-redirects" description:"Follow redirects"`
+	WarningTimeout          string   `short:"w" description:"Warning response time range in seconds ex. 4, 300ms, @1:2, 0 disables the check" default:""`
+	CriticalTimeout         string   `short:"c" description:"Critical response time range in seconds ex. 8, 500ms, ~:1.5, 0 disables the check" default:""`
+	PhaseWarnings           []string `long:"phase-warning" description:"Warning time range of request phase dns, connect, tls, ttfb or transfer ex. tls=200ms, can be repeated"`
+	PhaseCriticals          []string `long:"phase-critical" description:"Critical time range of request phase dns, connect, tls, ttfb or transfer ex. tls=500ms, can be repeated"`
+	ConnectTimeout          string   `long:"connect-timeout" description:"TCP connect timeout in seconds ex. 2, 500ms" default:""`
+	TLSTimeout              string   `long:"tls-timeout" description:"TLS handshake timeout in seconds ex. 2, 500ms" default:""`
+	HeaderTimeout           string   `long:"header-timeout" description:"Timeout for response headers after the request is sent in seconds ex. 5" default:""`
+	BodyTimeout             string   `long:"body-timeout" description:"Timeout for response body after headers are received in seconds ex. 10" default:""`
+	NoSNI                   bool     `long:"no-sni" description:"Do not use SNI"`
+	ClientCertFile          string   `short:"J" long:"client-cert" description:"Name of file containing the client certificate (PEM format) to be used in establishing the SSL session"`
+	PrivateKeyFile          string   `short:"K" long:"private-key" description:"Name of file containing the private key (PEM format) matching the client certificate"`
+	DisableTLSRenegotiation bool     `long:"disable-tls-renegotiation" description:"Disable TLS Renegotiation"`
+	Method                  string   `short:"j" long:"method" description:"HTTP method ex. GET, POST, PUT, HEAD (default: GET, POST if request body is given)" default:""`
+	Data                    string   `short:"P" long:"data" description:"Request body ex. foo=bar&baz=1" default:""`
+	DataFile                string   `long:"data-file" description:"Name of file containing the request body" default:""`
+	ContentType             string   `short:"T" long:"content-type" description:"Content-Type header of the request body (default: application/x-www-form-urlencoded)" default:""`
+	Headers                 []string `long:"header" description:"Custom request header ex. 'Accept: application/json', can be repeated"`
+	ExpectedHeaders         []string `long:"expect-header" description:"Expected response header with value regex ex. 'Cache-Control: max-age=\\d+', name only checks presence, can be repeated"`
+	ExpectedNoHeaders       []string `long:"expect-no-header" description:"Response header which must not be present ex. X-Powered-By, can be repeated"`
+	IgnoreCase              bool     `long:"ignore-case" description:"Search for given string in response body case-insensitively"`
+	Regex                   string   `short:"r" long:"ereg" description:"Search for given regular expression in response body" default:""`
+	RegexIgnoreCase         string   `short:"R" long:"eregi" description:"Search for given case-insensitive regular expression in response body" default:""`
+	InvertRegex             bool     `long:"invert-regex" description:"Return CRITICAL if the regular expression is found in response body"`
+	JSONPaths               []string `long:"json-path" description:"JSONPath to check in JSON response body ex. '$.status', can be repeated"`
+	JSONExpects             []string `long:"json-expect" description:"Expected value of the JSONPath given at the same position ex. UP, !=DOWN, >=5, ~^2\\., exists, !exists (default: exists)"`
+	JSONMetrics             []string `long:"json-metric" description:"Numeric JSONPath value exported as perfdata and checked against Nagios ranges ex. 'queue=$.queue.depth;100;500', can be repeated"`
+	XPaths                  []string `long:"xpath" description:"XPath to check in XML response body ex. '//faultstring', can be repeated"`
+	XPathExpects            []string `long:"xpath-expect" description:"Expected value of the XPath given at the same position, same syntax as --json-expect (default: exists)"`
+	Selectors               []string `long:"css" description:"CSS selector to check in HTML response body ex. 'div#status', can be repeated"`
+	SelectorExpects         []string `long:"css-expect" description:"Expected text of the CSS selector given at the same position, same syntax as --json-expect (default: exists)"`
+	JSONSchema              string   `long:"json-schema" description:"Name of file containing JSON Schema the response body must match" default:""`
+	HealthFormat            string   `long:"health-format" description:"Interpret response body as health check response" choice:"auto" choice:"spring" choice:"health+json"`
 }
 
 var options Options
@@ -90,13 +147,248 @@ func main() {
 		os.Exit(EXIT_UNKNOWN)
 	}
 
+	if options.AllAddresses && (len(options.Host) == 0 || len(options.IPAddress) > 0) {
+		fmt.Println("UNKNOWN - Option --all-addresses requires -H and cannot be combined with -I")
+		os.Exit(EXIT_UNKNOWN)
+	}
+
+	resolve := make(map[string]string)
+	for _, resolveOption := range options.Resolve {
+		resolveParts := strings.SplitN(resolveOption, ":", 3)
+		if len(resolveParts) != 3 || len(resolveParts[0]) == 0 || net.ParseIP(trimBrackets(resolveParts[2])) == nil {
+			fmt.Println(fmt.Sprintf("UNKNOWN - Invalid resolve entry '%s': provide e.g. --resolve example.com:443:10.0.0.1", resolveOption))
+			os.Exit(EXIT_UNKNOWN)
+		}
+		if _, err := strconv.Atoi(resolveParts[1]); err != nil {
+			fmt.Println(fmt.Sprintf("UNKNOWN - Invalid resolve entry '%s': provide e.g. --resolve example.com:443:10.0.0.1", resolveOption))
+			os.Exit(EXIT_UNKNOWN)
+		}
+		resolve[net.JoinHostPort(strings.ToLower(resolveParts[0]), resolveParts[1])] = trimBrackets(resolveParts[2])
+	}
+
+	dnsServer := options.DNSServer
+	if len(dnsServer) > 0 {
+		if _, _, err := net.SplitHostPort(dnsServer); err != nil {
+			dnsServer = net.JoinHostPort(trimBrackets(dnsServer), "53")
+		}
+	}
+
+	if len(options.UnixSocket) > 0 {
+		if len(options.IPAddress) > 0 || len(options.Proxy) > 0 || options.AllAddresses {
+			fmt.Println("UNKNOWN - Option --unix-socket cannot be combined with -I, --proxy or --all-addresses")
+			os.Exit(EXIT_UNKNOWN)
+		}
+		if len(options.Host) == 0 {
+			options.Host = "localhost"
+		}
+	}
+
+	if options.Protocol == PROTO_H2 && scheme != "https" {
+		fmt.Println("UNKNOWN - Protocol h2 requires TLS: use h2c for HTTP/2 without TLS")
+		os.Exit(EXIT_UNKNOWN)
+	}
+	if options.Protocol == PROTO_H2C && scheme == "https" {
+		fmt.Println("UNKNOWN - Protocol h2c cannot be used with TLS: use h2 for HTTP/2 over TLS")
+		os.Exit(EXIT_UNKNOWN)
+	}
+
+	if options.HTTP3 {
+		if scheme != "https" {
+			fmt.Println("UNKNOWN - Option --http3 requires TLS: provide --tls")
+			os.Exit(EXIT_UNKNOWN)
+		}
+		if len(options.Proxy) > 0 || len(options.UnixSocket) > 0 || len(options.Protocol) > 0 {
+			fmt.Println("UNKNOWN - Option --http3 cannot be combined with --proxy, --unix-socket or --protocol")
+			os.Exit(EXIT_UNKNOWN)
+		}
+	}
+	if options.HTTP3Compare && (!options.HTTP3 || options.AllAddresses) {
+		fmt.Println("UNKNOWN - Option --http3-compare requires --http3 and cannot be combined with --all-addresses")
+		os.Exit(EXIT_UNKNOWN)
+	}
+
+	if options.CASystem && len(options.CAFile) == 0 && len(options.CADir) == 0 {
+		fmt.Println("UNKNOWN - Option --ca-system requires --ca-file or --ca-dir")
+		os.Exit(EXIT_UNKNOWN)
+	}
+
+	var tlsMinVersion, tlsMaxVersion uint16
+	if len(options.TLSMin) > 0 {
+		tlsMinVersion, _ = ParseTLSVersion(options.TLSMin)
+	}
+	if len(options.TLSMax) > 0 {
+		tlsMaxVersion, _ = ParseTLSVersion(options.TLSMax)
+	}
+	if tlsMinVersion > 0 && tlsMaxVersion > 0 && tlsMinVersion > tlsMaxVersion {
+		fmt.Println("UNKNOWN - Minimal TLS version is greater than maximal TLS version")
+		os.Exit(EXIT_UNKNOWN)
+	}
+
+	var cipherSuites []uint16
+	if len(options.TLSCiphers) > 0 {
+		parsedSuites, err := ParseCipherSuites(options.TLSCiphers)
+		if err != nil {
+			fmt.Println(fmt.Sprintf("UNKNOWN - Option --tls-ciphers has %s", err.Error()))
+			os.Exit(EXIT_UNKNOWN)
+		}
+		cipherSuites = parsedSuites
+	}
+
+	if options.TLSScan {
+		if scheme != "https" {
+			fmt.Println("UNKNOWN - Option --tls-scan requires TLS: provide --tls")
+			os.Exit(EXIT_UNKNOWN)
+		}
+		if options.AllAddresses || options.HTTP3 || len(options.Proxy) > 0 {
+			fmt.Println("UNKNOWN - Option --tls-scan cannot be combined with --all-addresses, --http3 or --proxy")
+			os.Exit(EXIT_UNKNOWN)
+		}
+	}
+
+	proxy := Proxy{FromEnvironment: options.ProxyEnv}
+	if len(options.Proxy) > 0 {
+		proxyURL, err := url.Parse(options.Proxy)
+		if err != nil || len(proxyURL.Host) == 0 {
+			fmt.Println(fmt.Sprintf("UNKNOWN - Invalid proxy '%s': provide e.g. --proxy http://proxy:3128", options.Proxy))
+			os.Exit(EXIT_UNKNOWN)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			fmt.Println(fmt.Sprintf("UNKNOWN - Proxy scheme '%s' is not supported: use http, https or socks5", proxyURL.Scheme))
+			os.Exit(EXIT_UNKNOWN)
+		}
+		proxy.URL = proxyURL
+	}
+	if len(options.ProxyAuth) > 0 {
+		if proxy.URL == nil {
+			fmt.Println("UNKNOWN - Option --proxy-auth requires --proxy: provide e.g. --proxy http://proxy:3128")
+			os.Exit(EXIT_UNKNOWN)
+		}
+		proxyAuthParts := strings.SplitN(options.ProxyAuth, ":", 2)
+		if len(proxyAuthParts) != 2 || len(proxyAuthParts[0]) == 0 {
+			fmt.Println("UNKNOWN - Proxy username and password not given: provide --proxy-auth username:password")
+			os.Exit(EXIT_UNKNOWN)
+		}
+		proxy.Authentication = Authentication{Type: AUTH_BASIC, User: proxyAuthParts[0], Password: proxyAuthParts[1]}
+	}
+	if options.ProxyNtlm {
+		if proxy.URL == nil || proxy.URL.Scheme != "http" || proxy.Authentication.Type == AUTH_NONE {
+			fmt.Println("UNKNOWN - NTLM proxy auth requires HTTP proxy and credentials: provide --proxy http://proxy:3128 --proxy-auth username:password")
+			os.Exit(EXIT_UNKNOWN)
+		}
+		proxy.Authentication.Type = AUTH_NTLM
+	}
+
+	var addressFamily string
+	if options.IPv4 && options.IPv6 {
+		fmt.Println("UNKNOWN - Options -4 and -6 are mutually exclusive")
+		os.Exit(EXIT_UNKNOWN)
+	}
+	if options.IPv4 {
+		addressFamily = "tcp4"
+	}
+	if options.IPv6 {
+		addressFamily = "tcp6"
+	}
+
+	if len(options.Data) > 0 && len(options.DataFile) > 0 {
+		fmt.Println("UNKNOWN - Request body given twice: provide either --data or --data-file")
+		os.Exit(EXIT_UNKNOWN)
+	}
+
+	var body []byte
+	if len(options.Data) > 0 {
+		body = []byte(options.Data)
+	}
+	if len(options.DataFile) > 0 {
+		data, err := ioutil.ReadFile(options.DataFile)
+		if err != nil {
+			fmt.Println(fmt.Sprintf("UNKNOWN - Cannot read request body: %s", err.Error()))
+			os.Exit(EXIT_UNKNOWN)
+		}
+		body = data
+	}
+
+	method := options.Method
+	if len(method) == 0 && body != nil {
+		method = "POST"
+	}
+
+	contentType := options.ContentType
+	if len(contentType) == 0 && body != nil {
+		contentType = "application/x-www-form-urlencoded"
+	}
+
+	headers := http.Header{}
+	for _, header := range options.Headers {
+		headerParts := strings.SplitN(header, ":", 2)
+		if len(headerParts) != 2 || len(strings.TrimSpace(headerParts[0])) == 0 {
+			fmt.Println(fmt.Sprintf("UNKNOWN - Invalid header '%s': provide e.g. --header 'Accept: application/json'", header))
+			os.Exit(EXIT_UNKNOWN)
+		}
+		headers.Add(strings.TrimSpace(headerParts[0]), strings.TrimSpace(headerParts[1]))
+	}
+
+	timeout, err := ParseSeconds(options.Timeout)
+	if err != nil || timeout <= 0 {
+		fmt.Println("UNKNOWN - Timeout has invalid value: provide e.g. -t 10 or -t 500ms")
+		os.Exit(EXIT_UNKNOWN)
+	}
+
+	timeThreshold, err := ParseTimeThreshold(options.WarningTimeout, options.CriticalTimeout)
+	if err != nil {
+		fmt.Println(fmt.Sprintf("UNKNOWN - Response time threshold has %s: provide e.g. -w 300ms -c 1", err.Error()))
+		os.Exit(EXIT_UNKNOWN)
+	}
+
+	phaseRanges := make(map[string][2]string)
+	for i, phaseOptions := range [][]string{options.PhaseWarnings, options.PhaseCriticals} {
+		for _, phaseOption := range phaseOptions {
+			phaseParts := strings.SplitN(phaseOption, "=", 2)
+			if _, ok := phaseLookup[phaseParts[0]]; !ok || len(phaseParts) != 2 {
+				fmt.Println(fmt.Sprintf("UNKNOWN - Invalid phase threshold '%s': provide e.g. --phase-warning tls=200ms", phaseOption))
+				os.Exit(EXIT_UNKNOWN)
+			}
+			phaseRange := phaseRanges[phaseParts[0]]
+			phaseRange[i] = phaseParts[1]
+			phaseRanges[phaseParts[0]] = phaseRange
+		}
+	}
+
+	phaseThresholds := make(map[string]Threshold)
+	for phase, phaseRange := range phaseRanges {
+		phaseThresholds[phase], err = ParseTimeThreshold(phaseRange[0], phaseRange[1])
+		if err != nil {
+			fmt.Println(fmt.Sprintf("UNKNOWN - Phase %s threshold has %s: provide e.g. --phase-warning tls=200ms", phase, err.Error()))
+			os.Exit(EXIT_UNKNOWN)
+		}
+	}
+
+	phaseTimeouts := make(map[string]float64)
+	for name, phaseTimeout := range map[string]string{
+		"connect": options.ConnectTimeout,
+		"tls":     options.TLSTimeout,
+		"header":  options.HeaderTimeout,
+		"body":    options.BodyTimeout,
+	} {
+		if len(phaseTimeout) == 0 {
+			continue
+		}
+		phaseTimeouts[name], err = ParseSeconds(phaseTimeout)
+		if err != nil || phaseTimeouts[name] <= 0 {
+			fmt.Println(fmt.Sprintf("UNKNOWN - Option --%s-timeout has invalid value: provide e.g. --%s-timeout 2 or --%s-timeout 500ms", name, name, name))
+			os.Exit(EXIT_UNKNOWN)
+		}
+	}
+
 	r := &Request{
-		Host:      options.Host,
-		IPAddress: options.IPAddress,
+		Host:      trimBrackets(options.Host),
+		IPAddress: trimBrackets(options.IPAddress),
 		URI:       options.URI,
 		Port:      port,
 		Scheme:    scheme,
-		Timeout:   options.Timeout,
+		Timeout:   timeout,
 		Authentication: Authentication{
 			Type:     authType,
 			User:     authUser,
@@ -105,14 +397,37 @@ func main() {
 		SSLNoVerify:     options.SSLNoVerify,
 		Verbose:         options.Verbose,
 		FollowRedirects: options.FollowRedirects,
-		WarningTimeout:  options.WarningTimeout,
-		CriticalTimeout: options.CriticalTimeout,
+		TimeThreshold:   timeThreshold,
+		PhaseThresholds: phaseThresholds,
+		ConnectTimeout:  phaseTimeouts["connect"],
+		TLSTimeout:      phaseTimeouts["tls"],
+		HeaderTimeout:   phaseTimeouts["header"],
+		BodyTimeout:     phaseTimeouts["body"],
 		NoSNI:           options.NoSNI,
+		AddressFamily:   addressFamily,
+		Resolve:         resolve,
+		DNSServer:       dnsServer,
+		Proxy:           proxy,
+		UnixSocket:      options.UnixSocket,
+		Protocol:        options.Protocol,
+		HTTP3:           options.HTTP3,
 		ClientCert: ClientCert{
 			ClientCertFile: options.ClientCertFile,
 			PrivateKeyFile: options.PrivateKeyFile,
 		},
+		CA: CA{
+			File:        options.CAFile,
+			Dir:         options.CADir,
+			SystemRoots: options.CASystem,
+		},
 		TLSRenegotiation: !options.DisableTLSRenegotiation,
+		TLSMinVersion:    tlsMinVersion,
+		TLSMaxVersion:    tlsMaxVersion,
+		CipherSuites:     cipherSuites,
+		Method:           method,
+		Body:             body,
+		ContentType:      contentType,
+		Headers:          headers,
 	}
 
 	if options.GuessAuth {
@@ -149,17 +464,172 @@ func main() {
 		SSLCritical = 0
 	}
 
+	var headerChecks []HeaderCheck
+	for _, header := range options.ExpectedHeaders {
+		headerParts := strings.SplitN(header, ":", 2)
+		headerCheck := HeaderCheck{Name: strings.TrimSpace(headerParts[0])}
+		if len(headerCheck.Name) == 0 {
+			fmt.Println(fmt.Sprintf("UNKNOWN - Invalid expected header '%s': provide e.g. --expect-header 'Cache-Control: no-cache'", header))
+			os.Exit(EXIT_UNKNOWN)
+		}
+		if len(headerParts) == 2 {
+			pattern, err := regexp.Compile(strings.TrimSpace(headerParts[1]))
+			if err != nil {
+				fmt.Println(fmt.Sprintf("UNKNOWN - Invalid expected header regex '%s': %s", header, err.Error()))
+				os.Exit(EXIT_UNKNOWN)
+			}
+			headerCheck.Pattern = pattern
+		}
+		headerChecks = append(headerChecks, headerCheck)
+	}
+
+	if len(options.Regex) > 0 && len(options.RegexIgnoreCase) > 0 {
+		fmt.Println("UNKNOWN - Regular expression given twice: provide either --ereg or --eregi")
+		os.Exit(EXIT_UNKNOWN)
+	}
+
+	var bodyRegex *regexp.Regexp
+	if len(options.Regex) > 0 || len(options.RegexIgnoreCase) > 0 {
+		expr := options.Regex
+		if len(options.RegexIgnoreCase) > 0 {
+			expr = "(?i)" + options.RegexIgnoreCase
+		}
+		var err error
+		bodyRegex, err = regexp.Compile(expr)
+		if err != nil {
+			fmt.Println(fmt.Sprintf("UNKNOWN - Invalid regular expression: %s", err.Error()))
+			os.Exit(EXIT_UNKNOWN)
+		}
+	}
+
+	if len(options.JSONExpects) > len(options.JSONPaths) {
+		fmt.Println("UNKNOWN - More JSON expectations than JSON paths given: provide --json-path for every --json-expect")
+		os.Exit(EXIT_UNKNOWN)
+	}
+
+	var JSONChecks []JSONCheck
+	for i, path := range options.JSONPaths {
+		expectation := EXPECT_EXISTS
+		if i < len(options.JSONExpects) {
+			expectation = options.JSONExpects[i]
+		}
+		jsonCheck, err := ParseJSONCheck(path, expectation)
+		if err != nil {
+			fmt.Println(fmt.Sprintf("UNKNOWN - Invalid JSON check: %s", err.Error()))
+			os.Exit(EXIT_UNKNOWN)
+		}
+		JSONChecks = append(JSONChecks, jsonCheck)
+	}
+
+	var JSONMetrics []JSONMetric
+	for _, metric := range options.JSONMetrics {
+		jsonMetric, err := ParseJSONMetric(metric)
+		if err != nil {
+			fmt.Println(fmt.Sprintf("UNKNOWN - Invalid JSON metric: %s", err.Error()))
+			os.Exit(EXIT_UNKNOWN)
+		}
+		JSONMetrics = append(JSONMetrics, jsonMetric)
+	}
+
+	if len(options.XPathExpects) > len(options.XPaths) {
+		fmt.Println("UNKNOWN - More XPath expectations than XPaths given: provide --xpath for every --xpath-expect")
+		os.Exit(EXIT_UNKNOWN)
+	}
+
+	var XPathChecks []XPathCheck
+	for i, query := range options.XPaths {
+		expectation := EXPECT_EXISTS
+		if i < len(options.XPathExpects) {
+			expectation = options.XPathExpects[i]
+		}
+		xpathCheck, err := ParseXPathCheck(query, expectation)
+		if err != nil {
+			fmt.Println(fmt.Sprintf("UNKNOWN - Invalid XPath check: %s", err.Error()))
+			os.Exit(EXIT_UNKNOWN)
+		}
+		XPathChecks = append(XPathChecks, xpathCheck)
+	}
+
+	if len(options.SelectorExpects) > len(options.Selectors) {
+		fmt.Println("UNKNOWN - More CSS selector expectations than CSS selectors given: provide --css for every --css-expect")
+		os.Exit(EXIT_UNKNOWN)
+	}
+
+	var selectorChecks []SelectorCheck
+	for i, selector := range options.Selectors {
+		expectation := EXPECT_EXISTS
+		if i < len(options.SelectorExpects) {
+			expectation = options.SelectorExpects[i]
+		}
+		selectorCheck, err := ParseSelectorCheck(selector, expectation)
+		if err != nil {
+			fmt.Println(fmt.Sprintf("UNKNOWN - Invalid CSS selector check: %s", err.Error()))
+			os.Exit(EXIT_UNKNOWN)
+		}
+		selectorChecks = append(selectorChecks, selectorCheck)
+	}
+
+	var JSONSchema *jsonschema.Schema
+	if len(options.JSONSchema) > 0 {
+		var err error
+		JSONSchema, err = LoadJSONSchema(options.JSONSchema)
+		if err != nil {
+			fmt.Println(fmt.Sprintf("UNKNOWN - Cannot load JSON schema: %s", err.Error()))
+			os.Exit(EXIT_UNKNOWN)
+		}
+	}
+
+	var sizeRange *Range
+	if len(options.PageSize) > 0 {
+		var err error
+		sizeRange, err = ParseSizeRange(options.PageSize)
+		if err != nil {
+			fmt.Println("UNKNOWN - Page size has invalid parameters: provide e.g. -m 512, -m 512:20000 or -m :20000")
+			os.Exit(EXIT_UNKNOWN)
+		}
+	}
+
 	e := &Expected{
 		StatusCodes: statusCodes,
-		BodyText:    options.BodyText,
+		BodyTexts:   options.BodyTexts,
+		AnyBodyText: options.BodyTextsMode == "any",
 		SSLCheck: SSLCheck{
 			Run:          options.SSL,
 			DaysWarning:  SSLWarning,
 			DaysCritical: SSLCritical,
 		},
+		Headers:        headerChecks,
+		NoHeaders:      options.ExpectedNoHeaders,
+		IgnoreCase:     options.IgnoreCase,
+		BodyRegex:      bodyRegex,
+		InvertRegex:    options.InvertRegex,
+		JSONChecks:     JSONChecks,
+		JSONMetrics:    JSONMetrics,
+		HealthFormat:   options.HealthFormat,
+		XPathChecks:    XPathChecks,
+		SelectorChecks: selectorChecks,
+		JSONSchema:     JSONSchema,
+		SizeRange:      sizeRange,
+		Protocol:       options.ExpectProtocol,
 	}
 
-	msg, code, err := Check(r, e)
+	check := Check
+	if options.AllAddresses {
+		check = CheckAllAddresses
+	}
+	if options.HTTP3Compare {
+		check = CheckHTTP3Comparison
+	}
+	if options.TLSScan {
+		tlsScan := TLSScan{
+			LegacyVersionExit: severityLookup[options.TLSScanLegacy],
+			WeakCipherExit:    severityLookup[options.TLSScanWeak],
+		}
+		check = func(r *Request, e *Expected) (string, int, error) {
+			return CheckTLSScan(r, tlsScan)
+		}
+	}
+	msg, code, err := check(r, e)
 
 	if err != nil {
 		fmt.Println(fmt.Sprintf("UNKNOWN, %s", err.Error()))
@@ -169,3 +639,11 @@ func main() {
 	fmt.Println(msg)
 	os.Exit(code)
 }
+
+// Removes brackets around IPv6 address ex. [::1]
+func trimBrackets(host string) string {
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		return host[1 : len(host)-1]
+	}
+	return host
+}
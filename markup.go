@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+)
+
+// XPath assertion on XML response body
+type XPathCheck struct {
+	Query       string
+	Expr        *xpath.Expr
+	Expectation Expectation
+}
+
+// CSS selector assertion on HTML response body
+type SelectorCheck struct {
+	Selector    string
+	Expectation Expectation
+}
+
+// Parses XPath assertion, see ParseExpectation
+func ParseXPathCheck(query string, expectation string) (XPathCheck, error) {
+	expr, err := xpath.Compile(query)
+	if err != nil {
+		return XPathCheck{}, fmt.Errorf("XPath '%s' is invalid: %s", query, err.Error())
+	}
+
+	parsedExpectation, err := ParseExpectation(expectation)
+	if err != nil {
+		return XPathCheck{}, err
+	}
+
+	return XPathCheck{Query: query, Expr: expr, Expectation: parsedExpectation}, nil
+}
+
+// Parses CSS selector assertion, see ParseExpectation
+func ParseSelectorCheck(selector string, expectation string) (SelectorCheck, error) {
+	// Validate selector
+	if _, err := cascadia.ParseGroup(selector); err != nil {
+		return SelectorCheck{}, fmt.Errorf("CSS selector '%s' is invalid: %s", selector, err.Error())
+	}
+
+	parsedExpectation, err := ParseExpectation(expectation)
+	if err != nil {
+		return SelectorCheck{}, err
+	}
+
+	return SelectorCheck{Selector: selector, Expectation: parsedExpectation}, nil
+}
+
+// Evaluates XPath, node sets yield text of the first node, functions like count() yield their result
+func (c XPathCheck) Evaluate(document *xmlquery.Node) (string, bool) {
+	var value string
+	var found bool
+
+	switch result := c.Expr.Evaluate(xmlquery.CreateXPathNavigator(document)).(type) {
+	case *xpath.NodeIterator:
+		if result.MoveNext() {
+			value = strings.TrimSpace(result.Current().Value())
+			found = true
+		}
+	case float64:
+		value = strconv.FormatFloat(result, 'f', -1, 64)
+		found = true
+	case bool:
+		value = strconv.FormatBool(result)
+		found = true
+	case string:
+		value = result
+		found = true
+	}
+
+	return c.Expectation.Evaluate(fmt.Sprintf("XPath '%s'", c.Query), value, found)
+}
+
+// Evaluates CSS selector, matched elements yield text of the first element
+func (c SelectorCheck) Evaluate(document *goquery.Document) (string, bool) {
+	selection := document.Find(c.Selector).First()
+	value := strings.TrimSpace(selection.Text())
+	return c.Expectation.Evaluate(fmt.Sprintf("CSS selector '%s'", c.Selector), value, selection.Length() > 0)
+}
+
+// XPath check helper
+func checkXPath(body []byte, e *Expected) (string, int) {
+	document, err := xmlquery.Parse(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Sprintf("CRITICAL - Response body is not valid XML: %s", err.Error()), EXIT_CRITICAL
+	}
+
+	for _, xpathCheck := range e.XPathChecks {
+		if failure, ok := xpathCheck.Evaluate(document); !ok {
+			return fmt.Sprintf("CRITICAL - %s", failure), EXIT_CRITICAL
+		}
+	}
+
+	return "", EXIT_OK
+}
+
+// CSS selector check helper
+func checkSelectors(body []byte, e *Expected) (string, int) {
+	document, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Sprintf("CRITICAL - Response body is not valid HTML: %s", err.Error()), EXIT_CRITICAL
+	}
+
+	for _, selectorCheck := range e.SelectorChecks {
+		if failure, ok := selectorCheck.Evaluate(document); !ok {
+			return fmt.Sprintf("CRITICAL - %s", failure), EXIT_CRITICAL
+		}
+	}
+
+	return "", EXIT_OK
+}
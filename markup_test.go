@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestXPathChecks(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprint(w, `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <GetStatusResponse>
+      <status code="1">Operational</status>
+      <item>a</item>
+      <item>b</item>
+    </GetStatusResponse>
+  </soap:Body>
+</soap:Envelope>`)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		query       string
+		expectation string
+		code        int
+		msg         string
+	}{
+		{"//faultstring", EXPECT_NOT_EXISTS, EXIT_OK, "OK"},
+		{"//status", "Operational", EXIT_OK, "OK"},
+		{"//status/@code", "1", EXIT_OK, "OK"},
+		{"count(//item)", ">=2", EXIT_OK, "OK"},
+		{"count(//item)", ">5", EXIT_CRITICAL, "CRITICAL - XPath 'count(//item)' is '2', expected > 5"},
+		{"//status", "Down", EXIT_CRITICAL, "CRITICAL - XPath '//status' is 'Operational', expected 'Down'"},
+		{"//GetStatusResponse", EXPECT_NOT_EXISTS, EXIT_CRITICAL, "CRITICAL - XPath '//GetStatusResponse' found"},
+		{"//missing", EXPECT_EXISTS, EXIT_CRITICAL, "CRITICAL - XPath '//missing' not found"},
+	}
+
+	for _, test := range tests {
+		xpathCheck, err := ParseXPathCheck(test.query, test.expectation)
+		if err != nil {
+			t.Errorf("Unexpected error [query: %s]: %v", test.query, err)
+			continue
+		}
+
+		r := newTestRequest(ts, "/soap")
+		e := &Expected{
+			StatusCodes: []int{200},
+			XPathChecks: []XPathCheck{xpathCheck},
+		}
+
+		msg, code, err := Check(r, e)
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message [query: %s]: %s", test.query, msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code [query: %s]: %d", test.query, code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil [query: %s]", test.query)
+		}
+	}
+
+	if _, err := ParseXPathCheck("//[", EXPECT_EXISTS); err == nil {
+		t.Errorf("Invalid XPath accepted")
+	}
+}
+
+func TestSelectorChecks(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, `<html><body><div id="status"> Operational </div><ul class="nodes"><li>a</li><li>b</li></ul></body></html>`)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		selector    string
+		expectation string
+		code        int
+		msg         string
+	}{
+		{"div#status", "Operational", EXIT_OK, "OK"},
+		{"ul.nodes li", "a", EXIT_OK, "OK"},
+		{"div.error", EXPECT_NOT_EXISTS, EXIT_OK, "OK"},
+		{"div#status", "~(?i)degraded", EXIT_CRITICAL, "CRITICAL - CSS selector 'div#status' is 'Operational', expected to match '(?i)degraded'"},
+		{"#maintenance", EXPECT_EXISTS, EXIT_CRITICAL, "CRITICAL - CSS selector '#maintenance' not found"},
+	}
+
+	for _, test := range tests {
+		selectorCheck, err := ParseSelectorCheck(test.selector, test.expectation)
+		if err != nil {
+			t.Errorf("Unexpected error [selector: %s]: %v", test.selector, err)
+			continue
+		}
+
+		r := newTestRequest(ts, "/")
+		e := &Expected{
+			StatusCodes:    []int{200},
+			SelectorChecks: []SelectorCheck{selectorCheck},
+		}
+
+		msg, code, err := Check(r, e)
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message [selector: %s]: %s", test.selector, msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code [selector: %s]: %d", test.selector, code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil [selector: %s]", test.selector)
+		}
+	}
+
+	if _, err := ParseSelectorCheck("div[", EXPECT_EXISTS); err == nil {
+		t.Errorf("Invalid CSS selector accepted")
+	}
+}
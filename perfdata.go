@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Single perfdata metric, see https://nagios-plugins.org/doc/guidelines.html#AEN200
+type PerfData struct {
+	Label     string
+	Value     float64
+	UOM       string
+	Threshold Threshold
+	Min       *float64
+	Max       *float64
+}
+
+// Perfdata of one check
+type PerfDataList []PerfData
+
+// Formats perfdata number without exponent
+func formatPerfValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// Returns pointer to perfdata min/max value
+func perfBound(value float64) *float64 {
+	return &value
+}
+
+// Formats metric as 'label'=value[UOM];[warn];[crit];[min];[max]
+func (p PerfData) String() string {
+	label := p.Label
+	if strings.ContainsAny(label, " '=") {
+		label = fmt.Sprintf("'%s'", strings.Replace(label, "'", "''", -1))
+	}
+
+	fields := []string{fmt.Sprintf("%s=%s%s", label, formatPerfValue(p.Value), p.UOM), "", "", "", ""}
+	if p.Threshold.Warning != nil {
+		fields[1] = p.Threshold.Warning.Raw
+	}
+	if p.Threshold.Critical != nil {
+		fields[2] = p.Threshold.Critical.Raw
+	}
+	if p.Min != nil {
+		fields[3] = formatPerfValue(*p.Min)
+	}
+	if p.Max != nil {
+		fields[4] = formatPerfValue(*p.Max)
+	}
+
+	// Trailing empty fields can be dropped
+	for len(fields) > 1 && len(fields[len(fields)-1]) == 0 {
+		fields = fields[:len(fields)-1]
+	}
+	return strings.Join(fields, ";")
+}
+
+// Formats all metrics separated by space
+func (l PerfDataList) String() string {
+	metrics := make([]string, len(l))
+	for i, perfData := range l {
+		metrics[i] = perfData.String()
+	}
+	return strings.Join(metrics, " ")
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPerfDataString(t *testing.T) {
+	warning, _ := ParseRange("4")
+	critical, _ := ParseRange("@10:20")
+
+	tests := []struct {
+		perfData PerfData
+		expected string
+	}{
+		{PerfData{Label: "time", Value: 0.123, UOM: "s", Threshold: Threshold{Warning: warning, Critical: critical}, Min: perfBound(0), Max: perfBound(30)}, "time=0.123s;4;@10:20;0;30"},
+		{PerfData{Label: "size", Value: 1024, UOM: "B", Min: perfBound(0)}, "size=1024B;;;0"},
+		{PerfData{Label: "queue", Value: 12}, "queue=12"},
+		{PerfData{Label: "queue", Value: 12, Max: perfBound(100)}, "queue=12;;;;100"},
+		{PerfData{Label: "pool active", Value: 1e-7}, "'pool active'=0.0000001"},
+		{PerfData{Label: "it's", Value: 1}, "'it''s'=1"},
+	}
+
+	for _, test := range tests {
+		if test.perfData.String() != test.expected {
+			t.Errorf("Wrong perfdata: %s, expected %s", test.perfData.String(), test.expected)
+		}
+	}
+
+	list := PerfDataList{tests[1].perfData, tests[2].perfData}
+	if list.String() != "size=1024B;;;0 queue=12" {
+		t.Errorf("Wrong perfdata list: %s", list.String())
+	}
+}
+
+func TestTimePerfData(t *testing.T) {
+	timeThreshold, _ := ParseTimeThreshold("4", "8000ms")
+	r := &Request{Timeout: 30, TimeThreshold: timeThreshold}
+
+	perfData := PerfData{
+		Label:     "time",
+		UOM:       "s",
+		Threshold: r.TimeThreshold,
+		Min:       perfBound(0),
+		Max:       perfBound(r.EffectiveTimeout().Seconds()),
+	}
+
+	if !strings.HasSuffix(perfData.String(), "s;4;8;0;8") {
+		t.Errorf("Wrong time perfdata: %s", perfData.String())
+	}
+}
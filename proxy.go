@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-ntlmssp"
+)
+
+// Proxy settings, environment variables are used only if requested
+type Proxy struct {
+	URL             *url.URL
+	FromEnvironment bool
+	Authentication  Authentication
+}
+
+// Dial function of transport
+type dialFunc func(ctx context.Context, network string, address string) (net.Conn, error)
+
+// Lookup map for default proxy ports
+var proxyPortLookup = map[string]string{
+	"http":   "80",
+	"https":  "443",
+	"socks5": "1080",
+}
+
+// Returns proxy host and port, missing port is given by proxy scheme
+func proxyAddress(proxyURL *url.URL) string {
+	if len(proxyURL.Port()) > 0 {
+		return proxyURL.Host
+	}
+	return net.JoinHostPort(proxyURL.Hostname(), proxyPortLookup[proxyURL.Scheme])
+}
+
+// Configures transport proxy and returns round tripper using it, unix sockets are never proxied
+func setProxy(transport *http.Transport, r *Request, dial dialFunc) http.RoundTripper {
+	proxy := r.Proxy
+	switch {
+	case len(r.UnixSocket) > 0:
+		transport.Proxy = nil
+	case proxy.URL != nil && proxy.Authentication.Type == AUTH_NTLM:
+		// HTTP requests are forwarded to proxy, HTTPS requests use CONNECT tunnel established by dialer
+		transport.Proxy = http.ProxyURL(proxy.URL)
+		tunnel := transport.Clone()
+		tunnel.Proxy = nil
+		tunnel.DialContext = func(ctx context.Context, network string, address string) (net.Conn, error) {
+			conn, err := dial(ctx, network, proxyAddress(proxy.URL))
+			if err != nil {
+				return nil, err
+			}
+			if err := connectNTLM(ctx, conn, resolveAddress(r, address), proxy.Authentication); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			return conn, nil
+		}
+		return &ntlmProxyTransport{forward: transport, tunnel: tunnel, authentication: proxy.Authentication}
+	case proxy.URL != nil:
+		proxyURL := *proxy.URL
+		if proxy.Authentication.Type == AUTH_BASIC {
+			proxyURL.User = url.UserPassword(proxy.Authentication.User, proxy.Authentication.Password)
+		}
+		transport.Proxy = http.ProxyURL(&proxyURL)
+	case proxy.FromEnvironment:
+		transport.Proxy = http.ProxyFromEnvironment
+	default:
+		transport.Proxy = nil
+	}
+	return transport
+}
+
+// Round tripper for proxy with NTLM authentication, HTTP requests are forwarded and HTTPS requests are tunneled
+type ntlmProxyTransport struct {
+	forward        *http.Transport
+	tunnel         *http.Transport
+	authentication Authentication
+}
+
+// Sends request, forwarded requests are authenticated on proxy connection
+func (t *ntlmProxyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "http" {
+		return t.tunnel.RoundTrip(req)
+	}
+
+	user, domain := ntlmssp.GetDomain(t.authentication.User)
+	negotiate, err := ntlmssp.NewNegotiateMessage(domain, "")
+	if err != nil {
+		return nil, err
+	}
+	negotiateRequest, err := withProxyAuthorization(req, negotiate)
+	if err != nil {
+		return nil, err
+	}
+	res, err := t.forward.RoundTrip(negotiateRequest)
+	if err != nil || res.StatusCode != http.StatusProxyAuthRequired {
+		return res, err
+	}
+
+	// Proxy without NTLM support returns its response as is
+	challenge, err := parseNTLMChallenge(res.Header)
+	if err != nil || len(challenge) == 0 {
+		if err != nil {
+			res.Body.Close()
+		}
+		return res, err
+	}
+	// Drain body so the connection is reused for next handshake step
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	authenticate, err := ntlmssp.ProcessChallenge(challenge, user, t.authentication.Password)
+	if err != nil {
+		return nil, err
+	}
+	authenticateRequest, err := withProxyAuthorization(req, authenticate)
+	if err != nil {
+		return nil, err
+	}
+	return t.forward.RoundTrip(authenticateRequest)
+}
+
+// Closes idle connections of both transports
+func (t *ntlmProxyTransport) CloseIdleConnections() {
+	t.forward.CloseIdleConnections()
+	t.tunnel.CloseIdleConnections()
+}
+
+// Returns copy of request with NTLM Proxy-Authorization header, request body is rewound
+func withProxyAuthorization(req *http.Request, message []byte) (*http.Request, error) {
+	authorized := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		authorized.Body = body
+	}
+	authorized.Header.Set("Proxy-Authorization", "NTLM "+base64.StdEncoding.EncodeToString(message))
+	return authorized, nil
+}
+
+// Returns NTLM challenge from Proxy-Authenticate header, empty challenge if NTLM is not offered
+func parseNTLMChallenge(header http.Header) ([]byte, error) {
+	for _, value := range header.Values("Proxy-Authenticate") {
+		if strings.HasPrefix(value, "NTLM ") {
+			challenge, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, "NTLM "))
+			if err != nil {
+				return nil, fmt.Errorf("proxy returned invalid NTLM challenge: %s", err.Error())
+			}
+			return challenge, nil
+		}
+	}
+	return nil, nil
+}
+
+// Sends CONNECT request through connection, reader is shared by the whole handshake
+func proxyConnect(conn net.Conn, reader *bufio.Reader, address string, authorization string) (*http.Response, error) {
+	request := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: http.Header{"Proxy-Authorization": {authorization}},
+	}
+	if err := request.Write(conn); err != nil {
+		return nil, err
+	}
+	response, err := http.ReadResponse(reader, request)
+	if err != nil {
+		return nil, err
+	}
+	// Established tunnel has no body, otherwise drain body so the connection can be reused for next handshake step
+	if response.StatusCode == http.StatusOK {
+		return response, nil
+	}
+	_, err = io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+	return response, err
+}
+
+// Establishes CONNECT tunnel authenticated by NTLM
+func connectNTLM(ctx context.Context, conn net.Conn, address string, auth Authentication) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	user, domain := ntlmssp.GetDomain(auth.User)
+	negotiate, err := ntlmssp.NewNegotiateMessage(domain, "")
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := proxyConnect(conn, reader, address, "NTLM "+base64.StdEncoding.EncodeToString(negotiate))
+	if err != nil {
+		return err
+	}
+	if response.StatusCode == http.StatusOK {
+		return nil
+	}
+	if response.StatusCode != http.StatusProxyAuthRequired {
+		return fmt.Errorf("proxy returned %s", response.Status)
+	}
+
+	challenge, err := parseNTLMChallenge(response.Header)
+	if err != nil {
+		return err
+	}
+	if len(challenge) == 0 {
+		return fmt.Errorf("proxy does not support NTLM authentication")
+	}
+
+	authenticate, err := ntlmssp.ProcessChallenge(challenge, user, auth.Password)
+	if err != nil {
+		return err
+	}
+	response, err = proxyConnect(conn, reader, address, "NTLM "+base64.StdEncoding.EncodeToString(authenticate))
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxy returned %s", response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHTTPProxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		user, password, _ := parseProxyAuthorization(req.Header.Get("Proxy-Authorization"))
+		if !req.URL.IsAbs() || user != "user" || password != "secret" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		fmt.Fprintf(w, "proxied %s", req.Host)
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	r := &Request{
+		Scheme:  "http",
+		Host:    "backend.example.test",
+		Port:    80,
+		URI:     "/",
+		Timeout: 30,
+		Proxy: Proxy{
+			URL:            proxyURL,
+			Authentication: Authentication{Type: AUTH_BASIC, User: "user", Password: "secret"},
+		},
+	}
+	e := &Expected{
+		StatusCodes: []int{200},
+		BodyTexts:   []string{"proxied backend.example.test"},
+	}
+
+	msg, code, err := Check(r, e)
+
+	if !strings.HasPrefix(msg, "OK") {
+		t.Errorf("Wrong message: %s", msg)
+	}
+
+	if code != EXIT_OK {
+		t.Errorf("Wrong exit code: %d", code)
+	}
+
+	if err != nil {
+		t.Errorf("Returned error is not nil")
+	}
+}
+
+// Decodes basic Proxy-Authorization header
+func parseProxyAuthorization(header string) (string, string, bool) {
+	request := &http.Request{Header: http.Header{"Authorization": {header}}}
+	return request.BasicAuth()
+}
+
+// Minimal NTLM challenge message with unicode flag
+func ntlmChallenge() []byte {
+	challenge := make([]byte, 48)
+	copy(challenge, "NTLMSSP\x00")
+	binary.LittleEndian.PutUint32(challenge[8:], 2)
+	binary.LittleEndian.PutUint32(challenge[16:], 48)
+	binary.LittleEndian.PutUint32(challenge[20:], 1)
+	copy(challenge[24:], "12345678")
+	binary.LittleEndian.PutUint32(challenge[44:], 48)
+	return challenge
+}
+
+// Serves requests authenticated by NTLM, CONNECT requests are tunneled to target, other requests are answered by proxy
+func serveNTLMProxy(listener net.Listener, target string) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			for {
+				request, err := http.ReadRequest(reader)
+				if err != nil {
+					return
+				}
+				io.Copy(io.Discard, request.Body)
+				if request.Method != http.MethodConnect && !request.URL.IsAbs() {
+					return
+				}
+				message, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(request.Header.Get("Proxy-Authorization"), "NTLM "))
+				if len(message) < 12 {
+					fmt.Fprint(conn, "HTTP/1.1 407 Proxy Authentication Required\r\nContent-Length: 0\r\n\r\n")
+					return
+				}
+				switch binary.LittleEndian.Uint32(message[8:]) {
+				case 1:
+					fmt.Fprintf(conn, "HTTP/1.1 407 Proxy Authentication Required\r\nProxy-Authenticate: NTLM %s\r\nContent-Length: 4\r\n\r\ndeny", base64.StdEncoding.EncodeToString(ntlmChallenge()))
+				case 3:
+					if request.Method != http.MethodConnect {
+						body := fmt.Sprintf("forwarded %s", request.URL)
+						fmt.Fprintf(conn, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+						continue
+					}
+					backend, err := net.Dial("tcp", target)
+					if err != nil {
+						return
+					}
+					defer backend.Close()
+					fmt.Fprint(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+					go io.Copy(backend, reader)
+					io.Copy(conn, backend)
+					return
+				default:
+					return
+				}
+			}
+		}(conn)
+	}
+}
+
+func TestNTLMProxy(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, "tunneled")
+	}))
+	defer ts.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer listener.Close()
+	go serveNTLMProxy(listener, ts.Listener.Addr().String())
+
+	tests := []struct {
+		user string
+		code int
+		msg  string
+	}{
+		{"DOMAIN\\user", EXIT_OK, "OK"},
+		{"", EXIT_CRITICAL, "CRITICAL - "},
+	}
+
+	for _, test := range tests {
+		r := newTestRequest(ts, "/")
+		r.SSLNoVerify = true
+		r.Proxy = Proxy{
+			URL:            &url.URL{Scheme: "http", Host: listener.Addr().String()},
+			Authentication: Authentication{Type: AUTH_NTLM, User: test.user},
+		}
+		e := &Expected{
+			StatusCodes: []int{200},
+			BodyTexts:   []string{"tunneled"},
+		}
+
+		msg, code, err := Check(r, e)
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message [%s]: %s", test.user, msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code [%s]: %d", test.user, code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil [%s]", test.user)
+		}
+	}
+}
+
+func TestNTLMProxyForward(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer listener.Close()
+	go serveNTLMProxy(listener, "")
+
+	tests := []struct {
+		method string
+		user   string
+		code   int
+		msg    string
+	}{
+		{"GET", "DOMAIN\\user", EXIT_OK, "OK"},
+		{"POST", "DOMAIN\\user", EXIT_OK, "OK"},
+		{"GET", "", EXIT_CRITICAL, "CRITICAL - "},
+	}
+
+	for _, test := range tests {
+		r := &Request{
+			Scheme:  "http",
+			Host:    "backend.example.test",
+			Port:    8080,
+			URI:     "/",
+			Method:  test.method,
+			Timeout: 30,
+			Proxy: Proxy{
+				URL:            &url.URL{Scheme: "http", Host: listener.Addr().String()},
+				Authentication: Authentication{Type: AUTH_NTLM, User: test.user},
+			},
+		}
+		if test.method == "POST" {
+			r.Body = []byte("foo=bar")
+		}
+		e := &Expected{
+			StatusCodes: []int{200},
+			BodyTexts:   []string{"forwarded http://backend.example.test:8080/"},
+		}
+
+		msg, code, err := Check(r, e)
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message [%s %s]: %s", test.method, test.user, msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code [%s %s]: %d", test.method, test.user, code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil [%s %s]", test.method, test.user)
+		}
+	}
+}
+
+func TestProxyAddress(t *testing.T) {
+	tests := []struct {
+		proxy   string
+		address string
+	}{
+		{"http://proxy", "proxy:80"},
+		{"https://proxy", "proxy:443"},
+		{"socks5://proxy", "proxy:1080"},
+		{"http://proxy:3128", "proxy:3128"},
+		{"http://[2001:db8::1]", "[2001:db8::1]:80"},
+	}
+
+	for _, test := range tests {
+		proxyURL, _ := url.Parse(test.proxy)
+		if address := proxyAddress(proxyURL); address != test.address {
+			t.Errorf("Wrong proxy address [%s]: %s", test.proxy, address)
+		}
+	}
+}
+
+func TestProxyEnvironmentIgnored(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, "direct")
+	}))
+	defer ts.Close()
+
+	transport := &http.Transport{}
+	setProxy(transport, newTestRequest(ts, "/"), nil)
+	if transport.Proxy != nil {
+		t.Errorf("Proxy from environment used without --proxy-env")
+	}
+
+	r := newTestRequest(ts, "/")
+	r.Proxy.FromEnvironment = true
+	setProxy(transport, r, nil)
+	if transport.Proxy == nil {
+		t.Errorf("Proxy from environment not used with --proxy-env")
+	}
+}
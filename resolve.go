@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/antchfx/xpath"
+)
+
+// Result of check against single resolved address
+type AddressResult struct {
+	Address  string
+	Msg      string
+	ExitCode int
+}
+
+// Resolver factory, queries given DNS server instead of system resolvers
+func newResolver(r *Request) *net.Resolver {
+	if len(r.DNSServer) == 0 {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, r.DNSServer)
+		},
+	}
+}
+
+// Returns address pinned by --resolve, original address otherwise
+func resolveAddress(r *Request, address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	if pinned, ok := r.Resolve[net.JoinHostPort(strings.ToLower(host), port)]; ok {
+		return net.JoinHostPort(pinned, port)
+	}
+	return address
+}
+
+// Looks up all addresses of the host, address family is respected
+func lookupAddresses(ctx context.Context, r *Request) ([]string, error) {
+	ips, err := newResolver(r).LookupIPAddr(ctx, r.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	var addresses []string
+	for _, ip := range ips {
+		if r.AddressFamily == "tcp4" && ip.IP.To4() == nil {
+			continue
+		}
+		if r.AddressFamily == "tcp6" && ip.IP.To4() != nil {
+			continue
+		}
+		addresses = append(addresses, ip.IP.String())
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("no suitable address found for %s", r.Host)
+	}
+	return addresses, nil
+}
+
+// Returns status line of check output without state, perfdata and long output
+func checkSummary(msg string, exitCode int) string {
+	msg, _, _ = splitOutput(msg)
+	return strings.TrimPrefix(msg, exitLookup[exitCode]+" - ")
+}
+
+// Returns time and size perfdata of check output labeled by address ex. 10.0.0.1_time=0.1s
+func addressPerfData(address string, msg string) string {
+	_, perfData, _ := splitOutput(msg)
+	var metrics []string
+	for _, metric := range strings.Fields(perfData) {
+		if strings.HasPrefix(metric, "time=") || strings.HasPrefix(metric, "size=") {
+			metrics = append(metrics, fmt.Sprintf("%s_%s", address, metric))
+		}
+	}
+	return strings.Join(metrics, " ")
+}
+
+// Aggregates results of all addresses into single result
+func aggregateAddressResults(results []AddressResult) (string, int) {
+	exitCode := EXIT_OK
+	var failures []string
+	var longOutput []string
+	for _, result := range results {
+		summary := checkSummary(result.Msg, result.ExitCode)
+		longOutput = append(longOutput, fmt.Sprintf("%s: %s - %s", result.Address, exitLookup[result.ExitCode], summary))
+		if result.ExitCode == EXIT_OK {
+			continue
+		}
+		exitCode = worseExitCode(exitCode, result.ExitCode)
+		failures = append(failures, fmt.Sprintf("%s %s", result.Address, summary))
+	}
+
+	perfData := PerfDataList{
+		{Label: "backends", Value: float64(len(results)), Min: perfBound(0)},
+		{Label: "backends_failing", Value: float64(len(failures)), Min: perfBound(0), Max: perfBound(float64(len(results)))},
+	}.String()
+	for _, result := range results {
+		perfData = strings.TrimSpace(perfData + " " + addressPerfData(result.Address, result.Msg))
+	}
+
+	var msg string
+	if exitCode == EXIT_OK {
+		msg = fmt.Sprintf("OK - %d of %d backends OK", len(results), len(results))
+	} else {
+		msg = fmt.Sprintf("%s - %d of %d backends failing: %s", exitLookup[exitCode], len(failures), len(results), strings.Join(failures, ", "))
+	}
+	return formatOutput(msg, perfData, longOutput), exitCode
+}
+
+// Returns copy of expectations which can be used by concurrent check, compiled XPath expressions keep evaluation state
+func copyExpected(e *Expected) *Expected {
+	copied := *e
+	copied.XPathChecks = make([]XPathCheck, len(e.XPathChecks))
+	for i, xpathCheck := range e.XPathChecks {
+		copied.XPathChecks[i] = xpathCheck
+		copied.XPathChecks[i].Expr = xpath.MustCompile(xpathCheck.Query)
+	}
+	return &copied
+}
+
+// Checks every resolved address of the host concurrently, Host header and SNI are kept
+func CheckAllAddresses(r *Request, e *Expected) (string, int, error) {
+	if len(r.Host) == 0 {
+		return "UNKNOWN - No host given", EXIT_UNKNOWN, nil
+	}
+
+	addresses, err := lookupAddresses(context.Background(), r)
+	if err != nil {
+		return fmt.Sprintf("CRITICAL - Cannot resolve %s: %s", r.Host, err.Error()), EXIT_CRITICAL, nil
+	}
+
+	results := make([]AddressResult, len(addresses))
+	var wg sync.WaitGroup
+	for i, address := range addresses {
+		wg.Add(1)
+		go func(i int, address string) {
+			defer wg.Done()
+			addressRequest := *r
+			addressRequest.IPAddress = address
+			msg, code, err := Check(&addressRequest, copyExpected(e))
+			if err != nil {
+				msg, code = fmt.Sprintf("UNKNOWN - %s", err.Error()), EXIT_UNKNOWN
+			}
+			results[i] = AddressResult{Address: address, Msg: msg, ExitCode: code}
+		}(i, address)
+	}
+	wg.Wait()
+
+	msg, code := aggregateAddressResults(results)
+	return msg, code, nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAggregateAddressResults(t *testing.T) {
+	tests := []struct {
+		results []AddressResult
+		code    int
+		msg     string
+	}{
+		{
+			[]AddressResult{
+				{"10.0.0.1", "OK - Got response HTTP/1.1 200 OK|time=0.1s", EXIT_OK},
+				{"10.0.0.2", "OK - Got response HTTP/1.1 200 OK|time=0.1s", EXIT_OK},
+			},
+			EXIT_OK,
+			"OK - 2 of 2 backends OK|backends=2;;;0 backends_failing=0;;;0;2 10.0.0.1_time=0.1s 10.0.0.2_time=0.1s\n10.0.0.1: OK - Got response HTTP/1.1 200 OK\n10.0.0.2: OK - Got response HTTP/1.1 200 OK",
+		},
+		{
+			[]AddressResult{
+				{"10.0.0.1", "OK - Got response HTTP/1.1 200 OK|time=0.1s size=85B;;;0 backend_ok=1", EXIT_OK},
+				{"10.0.0.2", "WARNING - Response time 2s violates warning threshold 1|time=2s;1", EXIT_WARNING},
+				{"10.0.0.3", "CRITICAL - Timeout - No response recieved in 10 seconds|time=10s", EXIT_CRITICAL},
+				{"10.0.0.4", "OK - Got response HTTP/1.1 200 OK|time=0.1s", EXIT_OK},
+			},
+			EXIT_CRITICAL,
+			"CRITICAL - 2 of 4 backends failing: 10.0.0.2 Response time 2s violates warning threshold 1, 10.0.0.3 Timeout - No response recieved in 10 seconds|backends=4;;;0 backends_failing=2;;;0;4 10.0.0.1_time=0.1s 10.0.0.1_size=85B;;;0 10.0.0.2_time=2s;1 10.0.0.3_time=10s 10.0.0.4_time=0.1s\n",
+		},
+	}
+
+	for _, test := range tests {
+		msg, code := aggregateAddressResults(test.results)
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message: %s", msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code: %d", code)
+		}
+	}
+}
+
+func TestCopyExpected(t *testing.T) {
+	xpathCheck, err := ParseXPathCheck("//status", "UP")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	e := &Expected{StatusCodes: []int{200}, XPathChecks: []XPathCheck{xpathCheck}}
+
+	copied := copyExpected(e)
+
+	if copied.XPathChecks[0].Expr == e.XPathChecks[0].Expr {
+		t.Errorf("XPath expression is shared")
+	}
+	if copied.XPathChecks[0].Query != "//status" || copied.XPathChecks[0].Expectation != xpathCheck.Expectation {
+		t.Errorf("Wrong XPath check copy: %v", copied.XPathChecks[0])
+	}
+}
+
+func TestCheckAllAddresses(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, req.Host)
+	}))
+	defer ts.Close()
+
+	r := newTestRequest(ts, "/")
+	r.Host = "localhost"
+	r.AddressFamily = "tcp4"
+	e := &Expected{
+		StatusCodes: []int{200},
+		BodyTexts:   []string{"localhost"},
+	}
+
+	msg, code, err := CheckAllAddresses(r, e)
+
+	if !strings.HasPrefix(msg, "OK - 1 of 1 backends OK") || !strings.Contains(msg, "127.0.0.1: OK") || !strings.Contains(msg, " 127.0.0.1_time=") {
+		t.Errorf("Wrong message: %s", msg)
+	}
+
+	if code != EXIT_OK {
+		t.Errorf("Wrong exit code: %d", code)
+	}
+
+	if err != nil {
+		t.Errorf("Returned error is not nil")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/" {
+			http.Redirect(w, req, "/redirected", http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, req.Host)
+	}))
+	defer ts.Close()
+
+	r := newTestRequest(ts, "/")
+	r.Resolve = map[string]string{net.JoinHostPort("example.test", strconv.Itoa(r.Port)): r.Host}
+	r.Host = "example.test"
+	r.FollowRedirects = true
+	e := &Expected{
+		StatusCodes: []int{200},
+		BodyTexts:   []string{"example.test"},
+	}
+
+	msg, code, err := Check(r, e)
+
+	if !strings.HasPrefix(msg, "OK") {
+		t.Errorf("Wrong message: %s", msg)
+	}
+
+	if code != EXIT_OK {
+		t.Errorf("Wrong exit code: %d", code)
+	}
+
+	if err != nil {
+		t.Errorf("Returned error is not nil")
+	}
+}
+
+// Answers A queries with 127.0.0.1 and other queries with no records
+func serveDNS(conn net.PacketConn) {
+	buffer := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+		query := buffer[:n]
+		end := 12
+		for end < len(query) && query[end] != 0 {
+			end += int(query[end]) + 1
+		}
+		end += 5
+		if end > len(query) {
+			continue
+		}
+		response := append([]byte{query[0], query[1], 0x81, 0x80, 0, 1, 0, 0, 0, 0, 0, 0}, query[12:end]...)
+		if query[end-4] == 0 && query[end-3] == 1 {
+			response[7] = 1
+			response = append(response, 0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 127, 0, 0, 1)
+		}
+		conn.WriteTo(response, addr)
+	}
+}
+
+func TestDNSServer(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer conn.Close()
+	go serveDNS(conn)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, req.Host)
+	}))
+	defer ts.Close()
+
+	r := newTestRequest(ts, "/")
+	r.Host = "backend.example.test"
+	r.DNSServer = conn.LocalAddr().String()
+	e := &Expected{
+		StatusCodes: []int{200},
+		BodyTexts:   []string{"backend.example.test"},
+	}
+
+	msg, code, err := Check(r, e)
+
+	if !strings.HasPrefix(msg, "OK") {
+		t.Errorf("Wrong message: %s", msg)
+	}
+
+	if code != EXIT_OK {
+		t.Errorf("Wrong exit code: %d", code)
+	}
+
+	if err != nil {
+		t.Errorf("Returned error is not nil")
+	}
+
+	addresses, err := lookupAddresses(context.Background(), r)
+	if err != nil || len(addresses) != 1 || addresses[0] != "127.0.0.1" {
+		t.Errorf("Wrong addresses: %v, %v", addresses, err)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// Max number of schema violations listed in the output
+const SCHEMA_MAX_VIOLATIONS = 3
+
+// Loads and compiles JSON Schema file
+func LoadJSONSchema(path string) (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	return compiler.Compile(path)
+}
+
+// Lists leaf schema violations as `instance location: error`
+func schemaViolations(err *jsonschema.ValidationError) []string {
+	if len(err.Causes) > 0 {
+		var violations []string
+		for _, cause := range err.Causes {
+			violations = append(violations, schemaViolations(cause)...)
+		}
+		return violations
+	}
+
+	location := "/" + strings.Join(err.InstanceLocation, "/")
+	return []string{fmt.Sprintf("%s: %s", location, err.BasicOutput().Error.String())}
+}
+
+// JSON Schema check helper
+func checkJSONSchema(document interface{}, e *Expected) (string, int) {
+	err := e.JSONSchema.Validate(document)
+	if err == nil {
+		return "", EXIT_OK
+	}
+
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return fmt.Sprintf("CRITICAL - Response body does not match JSON schema: %s", err.Error()), EXIT_CRITICAL
+	}
+
+	violations := schemaViolations(validationErr)
+	if len(violations) == 0 {
+		violations = []string{validationErr.Error()}
+	}
+	msg := strings.Join(violations, "; ")
+	if len(violations) > SCHEMA_MAX_VIOLATIONS {
+		msg = fmt.Sprintf("%s (and %d more)", strings.Join(violations[:SCHEMA_MAX_VIOLATIONS], "; "), len(violations)-SCHEMA_MAX_VIOLATIONS)
+	}
+	return fmt.Sprintf("CRITICAL - Response body does not match JSON schema: %s", msg), EXIT_CRITICAL
+}
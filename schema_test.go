@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["status", "items"],
+	"properties": {
+		"status": {"enum": ["UP", "DOWN"]},
+		"items": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"required": ["id"],
+				"properties": {"id": {"type": "integer"}}
+			}
+		}
+	}
+}`
+
+func TestJSONSchema(t *testing.T) {
+	schemaFile := filepath.Join(t.TempDir(), "schema.json")
+	if err := ioutil.WriteFile(schemaFile, []byte(testSchema), 0644); err != nil {
+		t.Fatalf("Cannot write schema: %v", err)
+	}
+
+	schema, err := LoadJSONSchema(schemaFile)
+	if err != nil {
+		t.Fatalf("Cannot load schema: %v", err)
+	}
+
+	tests := []struct {
+		body string
+		code int
+		msg  string
+	}{
+		{`{"status": "UP", "items": [{"id": 1}, {"id": 2}]}`, EXIT_OK, "OK"},
+		{`{"status": "MAYBE", "items": []}`, EXIT_CRITICAL, "CRITICAL - Response body does not match JSON schema: /status: "},
+		{`{"status": "UP", "items": [{"id": "1"}, {}]}`, EXIT_CRITICAL, "CRITICAL - Response body does not match JSON schema: /items/0/id: "},
+		{`{"status": 1, "items": [{"id": "a"}, {"id": "b"}, {"id": "c"}, {"id": "d"}]}`, EXIT_CRITICAL, "(and 2 more)"},
+		{`[]`, EXIT_CRITICAL, "CRITICAL - Response body does not match JSON schema: /: "},
+		{`not json`, EXIT_CRITICAL, "CRITICAL - Response body is not valid JSON"},
+	}
+
+	for _, test := range tests {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(w, test.body)
+		}))
+
+		r := newTestRequest(ts, "/api")
+		e := &Expected{
+			StatusCodes: []int{200},
+			JSONSchema:  schema,
+		}
+
+		msg, code, err := Check(r, e)
+		ts.Close()
+
+		if !strings.Contains(msg, test.msg) {
+			t.Errorf("Wrong message [body: %s]: %s", test.body, msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code [body: %s]: %d", test.body, code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil [body: %s]", test.body)
+		}
+	}
+
+	if _, err := LoadJSONSchema(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("Missing schema file accepted")
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Nagios range, see https://nagios-plugins.org/doc/guidelines.html#THRESHOLDFORMAT
+type Range struct {
+	Raw    string
+	Start  float64
+	End    float64
+	Inside bool
+}
+
+// Warning and critical ranges
+type Threshold struct {
+	Warning  *Range
+	Critical *Range
+}
+
+// Parses range ex. 10, 10:, ~:10, 10:20, @10:20
+func ParseRange(raw string) (*Range, error) {
+	return parseRange(raw, parseNumber)
+}
+
+// Parses finite number, NaN and infinity are rejected
+func parseNumber(value string) (float64, error) {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, fmt.Errorf("'%s' is not a finite number", value)
+	}
+	return number, nil
+}
+
+// Parses time range in seconds, bounds accept ms and s units ex. 300ms, 0.5:2s, @100ms:200ms
+func ParseTimeRange(raw string) (*Range, error) {
+	r, err := parseRange(raw, ParseSeconds)
+	if err != nil {
+		return nil, err
+	}
+	// Perfdata is always in seconds
+	r.Raw = r.String()
+	return r, nil
+}
+
+// Parses page size range in bytes, single value is minimum ex. 512, 512:, :20000, 512:20000
+func ParseSizeRange(raw string) (*Range, error) {
+	r := &Range{Start: 0, End: math.Inf(1)}
+	minSize, maxSize, hasMax := strings.Cut(raw, ":")
+	if len(minSize) == 0 && len(maxSize) == 0 {
+		return nil, fmt.Errorf("invalid size range '%s'", raw)
+	}
+	if len(minSize) > 0 {
+		size, err := strconv.Atoi(minSize)
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("invalid size range '%s'", raw)
+		}
+		r.Start = float64(size)
+	}
+	if hasMax && len(maxSize) > 0 {
+		size, err := strconv.Atoi(maxSize)
+		if err != nil || float64(size) < r.Start {
+			return nil, fmt.Errorf("invalid size range '%s'", raw)
+		}
+		r.End = float64(size)
+	}
+	r.Raw = r.String()
+	return r, nil
+}
+
+// Parses seconds, ms and s units are accepted ex. 10, 1.5s, 300ms
+func ParseSeconds(value string) (float64, error) {
+	multiplier := 1.0
+	if strings.HasSuffix(value, "ms") {
+		multiplier = 0.001
+		value = strings.TrimSuffix(value, "ms")
+	} else {
+		value = strings.TrimSuffix(value, "s")
+	}
+	seconds, err := parseNumber(value)
+	if err != nil {
+		return 0, err
+	}
+	return seconds * multiplier, nil
+}
+
+func parseRange(raw string, parseValue func(string) (float64, error)) (*Range, error) {
+	r := &Range{Raw: raw, Start: 0, End: math.Inf(1)}
+
+	value := raw
+	if strings.HasPrefix(value, "@") {
+		r.Inside = true
+		value = value[1:]
+	}
+
+	var err error
+	if strings.Contains(value, ":") {
+		parts := strings.SplitN(value, ":", 2)
+		if parts[0] == "~" {
+			r.Start = math.Inf(-1)
+		} else if len(parts[0]) > 0 {
+			if r.Start, err = parseValue(parts[0]); err != nil {
+				return nil, fmt.Errorf("invalid range '%s'", raw)
+			}
+		}
+		if len(parts[1]) > 0 {
+			if r.End, err = parseValue(parts[1]); err != nil {
+				return nil, fmt.Errorf("invalid range '%s'", raw)
+			}
+		}
+	} else {
+		if r.End, err = parseValue(value); err != nil {
+			return nil, fmt.Errorf("invalid range '%s'", raw)
+		}
+	}
+
+	if r.Start > r.End {
+		return nil, fmt.Errorf("invalid range '%s': start is greater than end", raw)
+	}
+
+	return r, nil
+}
+
+// Formats range in Nagios syntax
+func (r Range) String() string {
+	var prefix string
+	if r.Inside {
+		prefix = "@"
+	}
+
+	var end string
+	if !math.IsInf(r.End, 1) {
+		end = strconv.FormatFloat(r.End, 'f', -1, 64)
+	}
+
+	switch {
+	case math.IsInf(r.Start, -1):
+		return fmt.Sprintf("%s~:%s", prefix, end)
+	case r.Start == 0 && len(end) > 0:
+		return fmt.Sprintf("%s%s", prefix, end)
+	default:
+		return fmt.Sprintf("%s%s:%s", prefix, strconv.FormatFloat(r.Start, 'f', -1, 64), end)
+	}
+}
+
+// Returns true if range is simple upper limit ex. 10
+func (r Range) IsUpperLimit() bool {
+	return !r.Inside && r.Start == 0 && !math.IsInf(r.End, 1)
+}
+
+// Returns true if value should raise an alert
+func (r Range) Alert(value float64) bool {
+	inRange := value >= r.Start && value <= r.End
+	if r.Inside {
+		return inRange
+	}
+	return !inRange
+}
+
+// Parses warning and critical range, empty string means no range
+func ParseThreshold(warning string, critical string) (Threshold, error) {
+	var threshold Threshold
+	var err error
+	if len(warning) > 0 {
+		if threshold.Warning, err = ParseRange(warning); err != nil {
+			return threshold, err
+		}
+	}
+	if len(critical) > 0 {
+		if threshold.Critical, err = ParseRange(critical); err != nil {
+			return threshold, err
+		}
+	}
+	return threshold, nil
+}
+
+// Parses warning and critical time range, empty string means no range, zero limit means no range as it was the former default
+func ParseTimeThreshold(warning string, critical string) (Threshold, error) {
+	var threshold Threshold
+	var err error
+	if len(warning) > 0 {
+		if threshold.Warning, err = parseOptionalTimeRange(warning); err != nil {
+			return threshold, err
+		}
+	}
+	if len(critical) > 0 {
+		if threshold.Critical, err = parseOptionalTimeRange(critical); err != nil {
+			return threshold, err
+		}
+	}
+	return threshold, nil
+}
+
+// Parses time range, nil is returned for zero limit ex. 0, 0ms
+func parseOptionalTimeRange(raw string) (*Range, error) {
+	r, err := ParseTimeRange(raw)
+	if err != nil {
+		return nil, err
+	}
+	if r.IsUpperLimit() && r.End == 0 {
+		return nil, nil
+	}
+	return r, nil
+}
+
+// Returns exit code for given value
+func (t Threshold) Evaluate(value float64) int {
+	if t.Critical != nil && t.Critical.Alert(value) {
+		return EXIT_CRITICAL
+	}
+	if t.Warning != nil && t.Warning.Alert(value) {
+		return EXIT_WARNING
+	}
+	return EXIT_OK
+}
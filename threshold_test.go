@@ -0,0 +1,212 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		raw    string
+		values map[float64]bool
+	}{
+		{"10", map[float64]bool{-1: true, 0: false, 10: false, 10.5: true}},
+		{"10:", map[float64]bool{9.9: true, 10: false, 1000: false}},
+		{"~:10", map[float64]bool{-1000: false, 10: false, 11: true}},
+		{"10:20", map[float64]bool{9: true, 10: false, 20: false, 21: true}},
+		{"@10:20", map[float64]bool{9: false, 10: true, 20: true, 21: false}},
+		{"0.25", map[float64]bool{0.2: false, 0.3: true}},
+	}
+
+	for _, test := range tests {
+		r, err := ParseRange(test.raw)
+		if err != nil {
+			t.Errorf("Unexpected error [range: %s]: %v", test.raw, err)
+			continue
+		}
+		for value, alert := range test.values {
+			if r.Alert(value) != alert {
+				t.Errorf("Wrong alert [range: %s, value: %v]", test.raw, value)
+			}
+		}
+	}
+
+	for _, raw := range []string{"", "abc", "20:10", "1:x", "@", "nan", "NaN", "inf", "-Inf:0", "1:+inf", "@nan:1"} {
+		if _, err := ParseRange(raw); err == nil {
+			t.Errorf("Invalid range accepted [range: %s]", raw)
+		}
+	}
+}
+
+func TestThresholdEvaluate(t *testing.T) {
+	threshold, err := ParseThreshold("100", "500")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if threshold.Evaluate(50) != EXIT_OK {
+		t.Errorf("Wrong exit code for OK value")
+	}
+
+	if threshold.Evaluate(150) != EXIT_WARNING {
+		t.Errorf("Wrong exit code for warning value")
+	}
+
+	if threshold.Evaluate(600) != EXIT_CRITICAL {
+		t.Errorf("Wrong exit code for critical value")
+	}
+
+	empty, _ := ParseThreshold("", "")
+	if empty.Evaluate(1e9) != EXIT_OK {
+		t.Errorf("Empty threshold should never alert")
+	}
+}
+
+func TestParseTimeRange(t *testing.T) {
+	tests := []struct {
+		raw   string
+		start float64
+		end   float64
+		perf  string
+	}{
+		{"10", 0, 10, "10"},
+		{"300ms", 0, 0.3, "0.3"},
+		{"1.5s", 0, 1.5, "1.5"},
+		{"@100ms:200ms", 0.1, 0.2, "@0.1:0.2"},
+		{"~:500ms", math.Inf(-1), 0.5, "~:0.5"},
+		{"2:", 2, math.Inf(1), "2:"},
+	}
+
+	for _, test := range tests {
+		r, err := ParseTimeRange(test.raw)
+		if err != nil {
+			t.Errorf("Unexpected error [range: %s]: %v", test.raw, err)
+			continue
+		}
+		if r.Start != test.start || r.End != test.end {
+			t.Errorf("Wrong bounds [range: %s]: %v:%v", test.raw, r.Start, r.End)
+		}
+		if r.Raw != test.perf {
+			t.Errorf("Wrong perfdata range [range: %s]: %s", test.raw, r.Raw)
+		}
+	}
+
+	for _, raw := range []string{"10m", "ms", "1s:0.5s", "nan", "inf", "infms", "1:NaNs"} {
+		if _, err := ParseTimeRange(raw); err == nil {
+			t.Errorf("Invalid time range accepted [range: %s]", raw)
+		}
+	}
+}
+
+func TestParseSizeRange(t *testing.T) {
+	tests := []struct {
+		raw   string
+		start float64
+		end   float64
+		perf  string
+	}{
+		{"512", 512, math.Inf(1), "512:"},
+		{"512:", 512, math.Inf(1), "512:"},
+		{":20000", 0, 20000, "20000"},
+		{"512:20000", 512, 20000, "512:20000"},
+		{"0:0", 0, 0, "0"},
+	}
+
+	for _, test := range tests {
+		r, err := ParseSizeRange(test.raw)
+		if err != nil {
+			t.Errorf("Unexpected error [-m %s]: %v", test.raw, err)
+			continue
+		}
+		if r.Start != test.start || r.End != test.end {
+			t.Errorf("Wrong bounds [-m %s]: %v:%v", test.raw, r.Start, r.End)
+		}
+		if r.Raw != test.perf {
+			t.Errorf("Wrong perfdata range [-m %s]: %s", test.raw, r.Raw)
+		}
+	}
+
+	for _, raw := range []string{"", ":", "-1", "abc", "512:100", "1.5", "@512", "~:512", "512:x"} {
+		if _, err := ParseSizeRange(raw); err == nil {
+			t.Errorf("Invalid size range accepted [-m %s]", raw)
+		}
+	}
+}
+
+func TestParseSeconds(t *testing.T) {
+	tests := []struct {
+		value   string
+		seconds float64
+	}{
+		{"10", 10},
+		{"1.5s", 1.5},
+		{"300ms", 0.3},
+	}
+
+	for _, test := range tests {
+		seconds, err := ParseSeconds(test.value)
+		if err != nil || seconds != test.seconds {
+			t.Errorf("Wrong seconds [-t %s]: %v, %v", test.value, seconds, err)
+		}
+	}
+
+	// Timeout -t inf would disable client timeout
+	for _, value := range []string{"inf", "Inf", "+infs", "-inf", "NaN", "nanms"} {
+		if _, err := ParseSeconds(value); err == nil {
+			t.Errorf("Invalid seconds accepted [-t %s]", value)
+		}
+	}
+}
+
+func TestParseTimeThreshold(t *testing.T) {
+	tests := []struct {
+		warning  string
+		critical string
+		value    float64
+		code     int
+	}{
+		{"0", "0", 100, EXIT_OK},
+		{"0ms", "0s", 100, EXIT_OK},
+		{"0", "8", 10, EXIT_CRITICAL},
+		{"4", "0", 5, EXIT_WARNING},
+		{"@0:1", "", 0.5, EXIT_WARNING},
+		{"", "0:1", 2, EXIT_CRITICAL},
+	}
+
+	for _, test := range tests {
+		threshold, err := ParseTimeThreshold(test.warning, test.critical)
+		if err != nil {
+			t.Errorf("Returned error is not nil [-w %s -c %s]: %v", test.warning, test.critical, err)
+			continue
+		}
+		if code := threshold.Evaluate(test.value); code != test.code {
+			t.Errorf("Wrong exit code [-w %s -c %s]: %d", test.warning, test.critical, code)
+		}
+	}
+}
+
+func TestEffectiveTimeout(t *testing.T) {
+	tests := []struct {
+		timeout  float64
+		critical string
+		expected time.Duration
+	}{
+		{30, "", 30 * time.Second},
+		{0.5, "", 500 * time.Millisecond},
+		{30, "8", 8 * time.Second},
+		{30, "0", 30 * time.Second},
+		{0, "8", 8 * time.Second},
+		{5, "8", 5 * time.Second},
+		{30, "@1:2", 30 * time.Second},
+		{30, "1:", 30 * time.Second},
+	}
+
+	for _, test := range tests {
+		timeThreshold, _ := ParseTimeThreshold("", test.critical)
+		r := Request{Timeout: test.timeout, TimeThreshold: timeThreshold}
+		if r.EffectiveTimeout() != test.expected {
+			t.Errorf("Wrong effective timeout [-t %v -c %s]: %v", test.timeout, test.critical, r.EffectiveTimeout())
+		}
+	}
+}
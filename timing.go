@@ -0,0 +1,164 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Request phases
+	PHASE_DNS      = "dns"
+	PHASE_CONNECT  = "connect"
+	PHASE_TLS      = "tls"
+	PHASE_TTFB     = "ttfb"
+	PHASE_TRANSFER = "transfer"
+)
+
+// Phases in request order
+var phases = []string{PHASE_DNS, PHASE_CONNECT, PHASE_TLS, PHASE_TTFB, PHASE_TRANSFER}
+
+// Lookup map for phase names
+var phaseLookup = map[string]string{
+	PHASE_DNS:      "DNS lookup",
+	PHASE_CONNECT:  "TCP connect",
+	PHASE_TLS:      "TLS handshake",
+	PHASE_TTFB:     "Time to first byte",
+	PHASE_TRANSFER: "Body transfer",
+}
+
+// Start and end of request phases, redirects overwrite earlier phases
+type Timing struct {
+	mutex  sync.Mutex
+	starts map[string]time.Time
+	ends   map[string]time.Time
+}
+
+func NewTiming() *Timing {
+	return &Timing{
+		starts: make(map[string]time.Time),
+		ends:   make(map[string]time.Time),
+	}
+}
+
+func (t *Timing) start(phase string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.starts[phase] = time.Now()
+	delete(t.ends, phase)
+}
+
+func (t *Timing) end(phase string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.ends[phase] = time.Now()
+}
+
+// Marks end of body transfer, starts with the first response byte
+func (t *Timing) BodyRead() {
+	t.end(PHASE_TRANSFER)
+}
+
+// Returns phase duration, false if phase did not finish
+func (t *Timing) Duration(phase string) (time.Duration, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	start, started := t.starts[phase]
+	end, ended := t.ends[phase]
+	if !started || !ended {
+		return 0, false
+	}
+	return end.Sub(start), true
+}
+
+// Client trace recording phases, time to first byte is measured from the written request
+func (t *Timing) ClientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.start(PHASE_DNS)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.end(PHASE_DNS)
+		},
+		ConnectStart: func(string, string) {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			// Parallel dials (happy eyeballs) keep the first start
+			_, started := t.starts[PHASE_CONNECT]
+			_, ended := t.ends[PHASE_CONNECT]
+			if !started || ended {
+				t.starts[PHASE_CONNECT] = time.Now()
+				delete(t.ends, PHASE_CONNECT)
+			}
+		},
+		ConnectDone: func(string, string, error) {
+			t.end(PHASE_CONNECT)
+		},
+		TLSHandshakeStart: func() {
+			t.start(PHASE_TLS)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.end(PHASE_TLS)
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.start(PHASE_TTFB)
+		},
+		GotFirstResponseByte: func() {
+			t.end(PHASE_TTFB)
+			t.start(PHASE_TRANSFER)
+		},
+	}
+}
+
+// Perfdata of finished phases
+func (t *Timing) PerfData(r *Request) PerfDataList {
+	var perfData PerfDataList
+	for _, phase := range phases {
+		duration, ok := t.Duration(phase)
+		if !ok {
+			continue
+		}
+		perfData = append(perfData, PerfData{
+			Label:     fmt.Sprintf("time_%s", phase),
+			Value:     duration.Seconds(),
+			UOM:       "s",
+			Threshold: r.PhaseThresholds[phase],
+			Min:       perfBound(0),
+		})
+	}
+	return perfData
+}
+
+// Request phases check helper
+func checkPhases(t *Timing, r *Request) (string, int) {
+	exitCode := EXIT_OK
+	var failures []string
+	for _, phase := range phases {
+		threshold, ok := r.PhaseThresholds[phase]
+		if !ok {
+			continue
+		}
+		duration, ok := t.Duration(phase)
+		if !ok {
+			continue
+		}
+		phaseExit := threshold.Evaluate(duration.Seconds())
+		if phaseExit == EXIT_OK {
+			continue
+		}
+		phaseThreshold := threshold.Warning
+		if phaseExit == EXIT_CRITICAL {
+			phaseThreshold = threshold.Critical
+		}
+		failures = append(failures, fmt.Sprintf("%s took %ss, %s threshold %s", phaseLookup[phase], formatPerfValue(duration.Seconds()), strings.ToLower(exitLookup[phaseExit]), phaseThreshold.Raw))
+		exitCode = worseExitCode(exitCode, phaseExit)
+	}
+
+	if exitCode == EXIT_OK {
+		return "", EXIT_OK
+	}
+	return fmt.Sprintf("%s - %s", exitLookup[exitCode], strings.Join(failures, ", ")), exitCode
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPhasesPerfData(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(200)
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprint(w, "OK")
+	}))
+	defer ts.Close()
+
+	r := newTestRequest(ts, "/")
+	r.SSLNoVerify = true
+	e := &Expected{
+		StatusCodes: []int{200},
+	}
+
+	msg, code, err := Check(r, e)
+
+	if !strings.HasPrefix(msg, "OK") {
+		t.Errorf("Wrong message: %s", msg)
+	}
+
+	for _, label := range []string{"time_connect=", "time_tls=", "time_ttfb=0.1", "time_transfer=0.1"} {
+		if !strings.Contains(msg, label) {
+			t.Errorf("Missing %s perfdata: %s", label, msg)
+		}
+	}
+
+	if strings.Contains(msg, "time_dns=") {
+		t.Errorf("DNS perfdata returned for IP address: %s", msg)
+	}
+
+	if code != EXIT_OK {
+		t.Errorf("Wrong exit code: %d", code)
+	}
+
+	if err != nil {
+		t.Errorf("Returned error is not nil")
+	}
+}
+
+func TestPhaseThresholds(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(200)
+		w.(http.Flusher).Flush()
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprint(w, "OK")
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		phase    string
+		warning  string
+		critical string
+		code     int
+		msg      string
+	}{
+		{PHASE_TTFB, "100ms", "150ms", EXIT_OK, "OK"},
+		{PHASE_TRANSFER, "100ms", "", EXIT_WARNING, "WARNING - Body transfer took 0.2"},
+		{PHASE_TRANSFER, "50ms", "150ms", EXIT_CRITICAL, "CRITICAL - Body transfer took 0.2"},
+	}
+
+	for _, test := range tests {
+		threshold, err := ParseTimeThreshold(test.warning, test.critical)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		r := newTestRequest(ts, "/")
+		r.PhaseThresholds = map[string]Threshold{test.phase: threshold}
+		e := &Expected{
+			StatusCodes: []int{200},
+		}
+
+		msg, code, err := Check(r, e)
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message [%s]: %s", test.phase, msg)
+		}
+
+		if !strings.Contains(msg, fmt.Sprintf("time_%s=", test.phase)) {
+			t.Errorf("Missing phase perfdata [%s]: %s", test.phase, msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code [%s]: %d", test.phase, code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil [%s]", test.phase)
+		}
+	}
+}
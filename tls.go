@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Lookup map for TLS versions
+var tlsVersionLookup = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Parses TLS version ex. 1.2
+func ParseTLSVersion(version string) (uint16, error) {
+	if parsed, ok := tlsVersionLookup[version]; ok {
+		return parsed, nil
+	}
+	return 0, fmt.Errorf("TLS version '%s' is not supported, use 1.0, 1.1, 1.2 or 1.3", version)
+}
+
+// Parses comma separated cipher suite names ex. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, insecure suites are accepted
+func ParseCipherSuites(list string) ([]uint16, error) {
+	suites := make(map[string]uint16)
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		suites[suite.Name] = suite.ID
+	}
+
+	var cipherSuites []uint16
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		id, ok := suites[name]
+		if !ok {
+			return nil, fmt.Errorf("cipher suite '%s' is not supported", name)
+		}
+		cipherSuites = append(cipherSuites, id)
+	}
+	return cipherSuites, nil
+}
+
+// Returns TLS version as number for perfdata ex. 1.2
+func tlsVersionValue(version uint16) float64 {
+	for name, value := range tlsVersionLookup {
+		if value == version {
+			number, _ := strconv.ParseFloat(name, 64)
+			return number
+		}
+	}
+	return 0
+}
+
+// Describes negotiated TLS version, cipher suite and key exchange curve
+func describeTLS(state *tls.ConnectionState) string {
+	description := fmt.Sprintf("%s, cipher %s", tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+	if state.CurveID != 0 {
+		description = fmt.Sprintf("%s, curve %s", description, state.CurveID)
+	}
+	return description
+}
+
+// Negotiated TLS version, cipher suite ID and key exchange curve ID as perfdata
+func tlsPerfData(state *tls.ConnectionState) PerfDataList {
+	perfData := PerfDataList{
+		{Label: "tls_version", Value: tlsVersionValue(state.Version)},
+		{Label: "tls_cipher", Value: float64(state.CipherSuite)},
+	}
+	if state.CurveID != 0 {
+		perfData = append(perfData, PerfData{Label: "tls_curve", Value: float64(state.CurveID)})
+	}
+	return perfData
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseTLSVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		parsed  uint16
+		err     bool
+	}{
+		{"1.0", tls.VersionTLS10, false},
+		{"1.2", tls.VersionTLS12, false},
+		{"1.3", tls.VersionTLS13, false},
+		{"3.0", 0, true},
+	}
+
+	for _, test := range tests {
+		parsed, err := ParseTLSVersion(test.version)
+		if parsed != test.parsed || (err != nil) != test.err {
+			t.Errorf("Wrong result [%s]: %d, %v", test.version, parsed, err)
+		}
+	}
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	suites, err := ParseCipherSuites("TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, TLS_RSA_WITH_3DES_EDE_CBC_SHA")
+	if err != nil || len(suites) != 2 || suites[0] != tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 || suites[1] != tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA {
+		t.Errorf("Wrong cipher suites: %v, %v", suites, err)
+	}
+
+	if _, err := ParseCipherSuites("TLS_UNKNOWN"); err == nil || err.Error() != "cipher suite 'TLS_UNKNOWN' is not supported" {
+		t.Errorf("Wrong error: %v", err)
+	}
+}
+
+func TestTLSConstraints(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "cipher %s", tls.CipherSuiteName(req.TLS.CipherSuite))
+	}))
+	ts.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	ts.StartTLS()
+	defer ts.Close()
+
+	tests := []struct {
+		minVersion   uint16
+		cipherSuites []uint16
+		code         int
+		msg          string
+	}{
+		{0, nil, EXIT_OK, "OK - Got response HTTP/1.1 200|"},
+		{tls.VersionTLS13, nil, EXIT_CRITICAL, "CRITICAL - "},
+		{tls.VersionTLS12, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}, EXIT_OK, "OK - Got response HTTP/1.1 200|"},
+	}
+
+	for _, test := range tests {
+		r := newTestRequest(ts, "/")
+		r.SSLNoVerify = true
+		r.TLSMinVersion = test.minVersion
+		r.CipherSuites = test.cipherSuites
+		e := &Expected{
+			StatusCodes: []int{200},
+			BodyTexts:   []string{"cipher TLS_ECDHE_RSA_WITH_"},
+		}
+		if len(test.cipherSuites) > 0 {
+			e.BodyTexts = []string{"cipher TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}
+		}
+
+		msg, code, err := Check(r, e)
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message [%d]: %s", test.minVersion, msg)
+		}
+
+		if code == EXIT_OK {
+			if !strings.Contains(msg, " tls_version=1.2 tls_cipher=") || !strings.HasSuffix(msg, fmt.Sprintf(" tls_curve=%d", tls.X25519)) {
+				t.Errorf("Missing TLS perfdata: %s", msg)
+			}
+		}
+		if len(test.cipherSuites) > 0 && !strings.Contains(msg, fmt.Sprintf(" tls_cipher=%d ", tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384)) {
+			t.Errorf("Cipher suite not used: %s", msg)
+		}
+
+		if strings.Contains(msg, "\n") {
+			t.Errorf("TLS description outside verbose mode: %s", msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code [%d]: %d", test.minVersion, code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil [%d]", test.minVersion)
+		}
+	}
+}
+
+func TestCustomCA(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, "OK")
+	}))
+	defer ts.Close()
+
+	caDir := t.TempDir()
+	caFile := filepath.Join(caDir, "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, caPEM, 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(caDir, "README"), []byte("not a certificate"), 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		ca   CA
+		days int
+		code int
+		msg  string
+	}{
+		{CA{}, 0, EXIT_CRITICAL, "CRITICAL - "},
+		{CA{File: caFile}, 0, EXIT_OK, "OK - Got response HTTP/1.1 200|"},
+		{CA{Dir: caDir}, 0, EXIT_OK, "OK - Got response HTTP/1.1 200|"},
+		{CA{File: caFile, SystemRoots: true}, 0, EXIT_OK, "OK - Got response HTTP/1.1 200|"},
+		// Test certificate expires in 2084, expiry check needs verified chains
+		{CA{File: caFile}, 365 * 100, EXIT_CRITICAL, "CRITICAL - SSL cert expires in "},
+	}
+
+	for _, test := range tests {
+		r := newTestRequest(ts, "/")
+		r.Host = "example.com"
+		r.IPAddress = "127.0.0.1"
+		r.CA = test.ca
+		e := &Expected{
+			StatusCodes: []int{200},
+			SSLCheck: SSLCheck{
+				Run:          true,
+				DaysCritical: test.days,
+			},
+		}
+
+		msg, code, err := Check(r, e)
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message [%+v]: %s", test.ca, msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code [%+v]: %d", test.ca, code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil [%+v]: %v", test.ca, err)
+		}
+	}
+}
+
+func TestLoadRootCAsErrors(t *testing.T) {
+	emptyDir := t.TempDir()
+	if _, err := loadRootCAs(CA{Dir: emptyDir}); err == nil || !strings.HasPrefix(err.Error(), "no certificates found in CA directory") {
+		t.Errorf("Wrong error: %v", err)
+	}
+
+	invalidFile := filepath.Join(emptyDir, "invalid.pem")
+	ioutil.WriteFile(invalidFile, []byte("invalid"), 0644)
+	if _, err := loadRootCAs(CA{File: invalidFile}); err == nil || !strings.HasPrefix(err.Error(), "no certificates found in CA file") {
+		t.Errorf("Wrong error: %v", err)
+	}
+}
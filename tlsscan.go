@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Legacy TLS versions reported by scan
+var legacyTLSVersions = []uint16{tls.VersionTLS10, tls.VersionTLS11}
+
+// Weak cipher group
+type CipherGroup struct {
+	Name   string
+	Suites []uint16
+}
+
+// Weak cipher groups reported by scan
+func weakCipherGroups() []CipherGroup {
+	groups := []CipherGroup{{Name: "RC4"}, {Name: "3DES"}, {Name: "CBC-SHA1"}}
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		switch {
+		case strings.Contains(suite.Name, "_RC4_"):
+			groups[0].Suites = append(groups[0].Suites, suite.ID)
+		case strings.Contains(suite.Name, "_3DES_"):
+			groups[1].Suites = append(groups[1].Suites, suite.ID)
+		case strings.Contains(suite.Name, "_CBC_") && strings.HasSuffix(suite.Name, "_SHA"):
+			groups[2].Suites = append(groups[2].Suites, suite.ID)
+		}
+	}
+	return groups
+}
+
+// Lookup map for severity options
+var severityLookup = map[string]int{
+	"ok":       EXIT_OK,
+	"warning":  EXIT_WARNING,
+	"critical": EXIT_CRITICAL,
+}
+
+// Severity of scan findings, EXIT_OK only reports findings
+type TLSScan struct {
+	LegacyVersionExit int
+	WeakCipherExit    int
+}
+
+// Returns true if handshake failed because offered versions or cipher suites were rejected, timeouts are not rejections
+func isHandshakeRejection(err error) bool {
+	// Alert received from server
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "remote error" {
+		return true
+	}
+	// Server selected version or cipher suite which was not offered
+	return strings.Contains(err.Error(), "tls: server selected unsupported protocol version") ||
+		strings.Contains(err.Error(), "tls: server chose an unconfigured cipher suite")
+}
+
+// Returns true if server closed connection during handshake, which is common way to reject legacy hello
+func isConnectionClosed(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// Performs single handshake, returns negotiated cipher suite if accepted or reason of rejection, dial errors and timeouts are returned as error
+func scanHandshake(r *Request, baseConfig *tls.Config, minVersion uint16, maxVersion uint16, cipherSuites []uint16) (string, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.EffectiveTimeout())
+	defer cancel()
+
+	host := r.Host
+	if len(r.IPAddress) > 0 {
+		host = r.IPAddress
+	}
+	conn, err := newDialFunc(r)(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(r.Port)))
+	if err != nil {
+		return "", false, err
+	}
+	defer conn.Close()
+
+	config := baseConfig.Clone()
+	config.InsecureSkipVerify = true
+	config.MinVersion = minVersion
+	config.MaxVersion = maxVersion
+	config.CipherSuites = cipherSuites
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		switch {
+		case isHandshakeRejection(err):
+			return "", false, nil
+		case isConnectionClosed(err):
+			return "connection closed", false, nil
+		}
+		return "", false, err
+	}
+	return tls.CipherSuiteName(tlsConn.ConnectionState().CipherSuite), true, nil
+}
+
+// Formats rejected handshake for long output ex. TLS 1.0: rejected (connection closed)
+func rejectedOutput(name string, reason string) string {
+	if len(reason) > 0 {
+		return fmt.Sprintf("%s: rejected (%s)", name, reason)
+	}
+	return fmt.Sprintf("%s: rejected", name)
+}
+
+// Scans accepted TLS versions and weak cipher groups
+func CheckTLSScan(r *Request, s TLSScan) (string, int, error) {
+	if len(r.Host) == 0 && len(r.IPAddress) == 0 {
+		return "UNKNOWN - No host or IP address given", EXIT_UNKNOWN, nil
+	}
+
+	baseConfig, err := getTLSConfig(r)
+	if err != nil {
+		return "CRITICAL", EXIT_CRITICAL, err
+	}
+
+	// Insecure suites are offered so that the server picks any suite it accepts
+	var allSuites []uint16
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		allSuites = append(allSuites, suite.ID)
+	}
+
+	exitCode := EXIT_OK
+	var findings []string
+	var longOutput []string
+	handshakes := 0
+	legacyVersions := 0
+	weakCiphers := 0
+
+	for _, version := range []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13} {
+		result, accepted, err := scanHandshake(r, baseConfig, version, version, allSuites)
+		if err != nil {
+			return fmt.Sprintf("CRITICAL - %s handshake failed: %s", tls.VersionName(version), err.Error()), EXIT_CRITICAL, nil
+		}
+		if !accepted {
+			longOutput = append(longOutput, rejectedOutput(tls.VersionName(version), result))
+			continue
+		}
+		handshakes++
+		longOutput = append(longOutput, fmt.Sprintf("%s: accepted (%s)", tls.VersionName(version), result))
+		for _, legacyVersion := range legacyTLSVersions {
+			if version == legacyVersion {
+				legacyVersions++
+				findings = append(findings, tls.VersionName(version))
+				exitCode = worseExitCode(exitCode, s.LegacyVersionExit)
+			}
+		}
+	}
+
+	for _, group := range weakCipherGroups() {
+		result, accepted, err := scanHandshake(r, baseConfig, tls.VersionTLS10, tls.VersionTLS12, group.Suites)
+		if err != nil {
+			return fmt.Sprintf("CRITICAL - %s ciphers handshake failed: %s", group.Name, err.Error()), EXIT_CRITICAL, nil
+		}
+		if !accepted {
+			longOutput = append(longOutput, rejectedOutput(group.Name+" ciphers", result))
+			continue
+		}
+		handshakes++
+		weakCiphers++
+		longOutput = append(longOutput, fmt.Sprintf("%s ciphers: accepted (%s)", group.Name, result))
+		findings = append(findings, fmt.Sprintf("%s ciphers", group.Name))
+		exitCode = worseExitCode(exitCode, s.WeakCipherExit)
+	}
+
+	perfData := PerfDataList{
+		{Label: "tls_legacy_versions", Value: float64(legacyVersions), Min: perfBound(0), Max: perfBound(float64(len(legacyTLSVersions)))},
+		{Label: "tls_weak_ciphers", Value: float64(weakCiphers), Min: perfBound(0), Max: perfBound(float64(len(weakCipherGroups())))},
+	}
+
+	if handshakes == 0 {
+		return formatOutput("CRITICAL - No TLS handshake succeeded", perfData.String(), longOutput), EXIT_CRITICAL, nil
+	}
+	if len(findings) == 0 {
+		return formatOutput("OK - No legacy TLS versions or weak ciphers accepted", perfData.String(), longOutput), EXIT_OK, nil
+	}
+	return formatOutput(fmt.Sprintf("%s - Server accepts %s", exitLookup[exitCode], strings.Join(findings, ", ")), perfData.String(), longOutput), exitCode, nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTLSScan(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, "OK")
+	})
+
+	// Go defaults still accept CBC-SHA1 cipher suites
+	strict := httptest.NewUnstartedServer(handler)
+	strict.TLS = &tls.Config{
+		CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+	}
+	strict.StartTLS()
+	defer strict.Close()
+
+	legacy := httptest.NewUnstartedServer(handler)
+	legacy.TLS = &tls.Config{
+		MinVersion: tls.VersionTLS10,
+		MaxVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+		},
+	}
+	legacy.StartTLS()
+	defer legacy.Close()
+
+	// Legacy hello is answered by closing connection instead of alert
+	closing := httptest.NewUnstartedServer(handler)
+	closing.TLS = &tls.Config{
+		CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			for _, version := range hello.SupportedVersions {
+				if version >= tls.VersionTLS12 {
+					return nil, nil
+				}
+			}
+			hello.Conn.Close()
+			return nil, fmt.Errorf("legacy hello")
+		},
+	}
+	closing.StartTLS()
+	defer closing.Close()
+
+	tests := []struct {
+		ts   *httptest.Server
+		scan TLSScan
+		code int
+		msg  string
+	}{
+		{strict, TLSScan{EXIT_WARNING, EXIT_CRITICAL}, EXIT_OK, "OK - No legacy TLS versions or weak ciphers accepted|tls_legacy_versions=0;;;0;2 tls_weak_ciphers=0;;;0;3\nTLS 1.0: rejected\nTLS 1.1: rejected\nTLS 1.2: accepted"},
+		{closing, TLSScan{EXIT_WARNING, EXIT_CRITICAL}, EXIT_OK, "OK - No legacy TLS versions or weak ciphers accepted|tls_legacy_versions=0;;;0;2 tls_weak_ciphers=0;;;0;3\nTLS 1.0: rejected (connection closed)\nTLS 1.1: rejected (connection closed)\nTLS 1.2: accepted"},
+		{legacy, TLSScan{EXIT_WARNING, EXIT_CRITICAL}, EXIT_CRITICAL, "CRITICAL - Server accepts TLS 1.0, TLS 1.1, 3DES ciphers, CBC-SHA1 ciphers|tls_legacy_versions=2;;;0;2 tls_weak_ciphers=2;;;0;3\n"},
+		{legacy, TLSScan{EXIT_WARNING, EXIT_OK}, EXIT_WARNING, "WARNING - Server accepts TLS 1.0, TLS 1.1, 3DES ciphers, CBC-SHA1 ciphers|"},
+		{legacy, TLSScan{EXIT_OK, EXIT_OK}, EXIT_OK, "OK - Server accepts TLS 1.0, TLS 1.1, 3DES ciphers, CBC-SHA1 ciphers|"},
+	}
+
+	for _, test := range tests {
+		r := newTestRequest(test.ts, "/")
+
+		msg, code, err := CheckTLSScan(r, test.scan)
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message: %s", msg)
+		}
+
+		if code != test.code {
+			t.Errorf("Wrong exit code: %d", code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil")
+		}
+	}
+
+	if msg, _, _ := CheckTLSScan(newTestRequest(legacy, "/"), TLSScan{}); !strings.Contains(msg, "\n3DES ciphers: accepted (TLS_RSA_WITH_3DES_EDE_CBC_SHA)\n") || !strings.Contains(msg, "\nRC4 ciphers: rejected\n") {
+		t.Errorf("Wrong long output: %s", msg)
+	}
+}
+
+func TestTLSScanNetworkErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		handle func(conn net.Conn)
+		msg    string
+	}{
+		{"timeout", func(conn net.Conn) { time.Sleep(time.Second); conn.Close() }, "CRITICAL - TLS 1.0 handshake failed: "},
+		{"closed", func(conn net.Conn) { conn.Close() }, "CRITICAL - No TLS handshake succeeded|tls_legacy_versions=0;;;0;2 tls_weak_ciphers=0;;;0;3\nTLS 1.0: rejected (connection closed)\n"},
+	}
+
+	for _, test := range tests {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				go test.handle(conn)
+			}
+		}()
+
+		r := &Request{
+			Scheme:  "https",
+			Host:    "127.0.0.1",
+			Port:    listener.Addr().(*net.TCPAddr).Port,
+			Timeout: 0.2,
+		}
+
+		msg, code, err := CheckTLSScan(r, TLSScan{EXIT_WARNING, EXIT_CRITICAL})
+		listener.Close()
+
+		if !strings.HasPrefix(msg, test.msg) {
+			t.Errorf("Wrong message [%s]: %s", test.name, msg)
+		}
+
+		if code != EXIT_CRITICAL {
+			t.Errorf("Wrong exit code [%s]: %d", test.name, code)
+		}
+
+		if err != nil {
+			t.Errorf("Returned error is not nil [%s]", test.name)
+		}
+	}
+}